@@ -2,6 +2,7 @@ package critbit
 
 import (
 	"math/rand/v2"
+	"sync"
 	"testing"
 	"unsafe"
 )
@@ -10,12 +11,12 @@ func Comparable(k Key) string {
 	return unsafe.String(unsafe.SliceData(k.Data), len(k.Data))
 }
 
-func BenchmarkSet(b *testing.B) {
+func benchmarkSet(b *testing.B, m *Tree[uint32]) {
+	b.ReportAllocs()
 	N := 1024 * 256
 	dataset := setupDataset(N)
 	r := rand.New(rand.NewPCG(1, 1))
 	perm := r.Perm(N)
-	var m Tree[uint32]
 	for _, p := range perm[:N/2] {
 		data := dataset[p]
 		m.Set(data.Key, data.Value)
@@ -29,6 +30,15 @@ func BenchmarkSet(b *testing.B) {
 	}
 }
 
+func BenchmarkSet(b *testing.B) {
+	benchmarkSet(b, new(Tree[uint32]))
+}
+
+// BenchmarkCountedSet measures the cost of maintaining subtree counts.
+func BenchmarkCountedSet(b *testing.B) {
+	benchmarkSet(b, NewCountedTree[uint32]())
+}
+
 func BenchmarkGet(b *testing.B) {
 	N := 1024 * 256
 	dataset := setupDataset(N)
@@ -135,3 +145,155 @@ func BenchmarkOverhead(b *testing.B) {
 		n = (n + 1) % N
 	}
 }
+
+// routeLen is a prefix length with its share of a routing table
+// in percent.
+type routeLen struct {
+	bits    int
+	percent int
+}
+
+// ipv4Dist and ipv6Dist approximate the prefix length distribution
+// of full Internet routing tables.
+var (
+	ipv4Dist = []routeLen{
+		{8, 1}, {12, 1}, {16, 3}, {17, 1}, {18, 2}, {19, 3},
+		{20, 5}, {21, 5}, {22, 12}, {23, 8}, {24, 59},
+	}
+	ipv6Dist = []routeLen{
+		{29, 3}, {32, 20}, {36, 3}, {40, 6}, {44, 8},
+		{46, 3}, {47, 2}, {48, 50}, {56, 3}, {64, 2},
+	}
+)
+
+// setupRoutes returns n distinct random prefixes of addresses of the
+// given size in bytes, with prefix lengths following dist.
+func setupRoutes(r *rand.Rand, n, size int, dist []routeLen) []Key {
+	seen := make(map[string]bool)
+	routes := make([]Key, 0, n)
+	for len(routes) < n {
+		p := r.IntN(100)
+		nbits := dist[len(dist)-1].bits
+		for _, d := range dist {
+			if p < d.percent {
+				nbits = d.bits
+				break
+			}
+			p -= d.percent
+		}
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(r.Uint32())
+		}
+		// Clear the bits beyond the prefix length
+		for i := nbits; i < size*8; i++ {
+			data[i>>3] &^= 0x80 >> (i & 7)
+		}
+		key := BitsKey(data, nbits)
+		if s := string(data) + string(rune(nbits)); !seen[s] {
+			seen[s] = true
+			routes = append(routes, key)
+		}
+	}
+	return routes
+}
+
+// setupQueries returns n random host addresses of the given size in
+// bytes. Half of them fall within one of routes.
+func setupQueries(r *rand.Rand, n, size int, routes []Key) []Key {
+	queries := make([]Key, n)
+	for i := range queries {
+		data := make([]byte, size)
+		for j := range data {
+			data[j] = byte(r.Uint32())
+		}
+		if i%2 == 0 {
+			route := routes[r.IntN(len(routes))]
+			copy(data, route.Data[:route.Nbits>>3])
+			for j := route.Nbits &^ 7; j < route.Nbits; j++ {
+				mask := byte(0x80) >> (j & 7)
+				data[j>>3] = data[j>>3]&^mask | route.Data[j>>3]&mask
+			}
+		}
+		queries[i] = BytesKey(data)
+	}
+	return queries
+}
+
+// routingTable is a routing table with its lookup queries.
+type routingTable struct {
+	tree    Tree[int]
+	queries []Key
+}
+
+// newRoutingTable builds a routing table of n routes.
+func newRoutingTable(n, size int, dist []routeLen) *routingTable {
+	r := rand.New(rand.NewPCG(1, 1))
+	t := new(routingTable)
+	routes := setupRoutes(r, n, size, dist)
+	for i, key := range routes {
+		t.tree.Set(key, i)
+	}
+	t.queries = setupQueries(r, 1024*64, size, routes)
+	return t
+}
+
+var (
+	// full-size IPv4 table
+	ipv4Table = sync.OnceValue(func() *routingTable {
+		return newRoutingTable(1000*1000, 4, ipv4Dist)
+	})
+	// full-size IPv6 table
+	ipv6Table = sync.OnceValue(func() *routingTable {
+		return newRoutingTable(200*1000, 16, ipv6Dist)
+	})
+	// host routes only, with no route covering the queries,
+	// so a miss cannot be resolved near the leaf
+	sparseTable = sync.OnceValue(func() *routingTable {
+		t := newRoutingTable(1024*64, 4, []routeLen{{32, 100}})
+		for i := range t.queries {
+			t.queries[i] = Uint32Key(uint32(i) | 0xff000000)
+		}
+		return t
+	})
+)
+
+func benchmarkLongest(b *testing.B, t *routingTable) {
+	n := 0
+	for b.Loop() {
+		_, _ = t.tree.Longest(t.queries[n])
+		n = (n + 1) % len(t.queries)
+	}
+}
+
+func benchmarkLongestRecursive(b *testing.B, t *routingTable) {
+	n := 0
+	for b.Loop() {
+		_ = longestRecursive(t.tree.root, t.queries[n])
+		n = (n + 1) % len(t.queries)
+	}
+}
+
+func BenchmarkLongestIPv4(b *testing.B) {
+	benchmarkLongest(b, ipv4Table())
+}
+
+func BenchmarkLongestIPv6(b *testing.B) {
+	benchmarkLongest(b, ipv6Table())
+}
+
+func BenchmarkLongestSparse(b *testing.B) {
+	benchmarkLongest(b, sparseTable())
+}
+
+func BenchmarkLongestRecursiveIPv4(b *testing.B) {
+	benchmarkLongestRecursive(b, ipv4Table())
+}
+
+func BenchmarkLongestRecursiveIPv6(b *testing.B) {
+	benchmarkLongestRecursive(b, ipv6Table())
+}
+
+func BenchmarkLongestRecursiveSparse(b *testing.B) {
+	benchmarkLongestRecursive(b, sparseTable())
+}
@@ -0,0 +1,143 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// checkTree verifies that m holds exactly the entries of want, given as
+// values indexed by the position of their key in dataset, with -1 for
+// absent keys.
+func checkTree(t *testing.T, m *Tree[uint32], dataset []TestData, want []int) {
+	t.Helper()
+	var keys []uint32
+	for i, v := range want {
+		if v >= 0 {
+			keys = append(keys, uint32(i))
+		}
+	}
+	if m.Len() != len(keys) {
+		t.Fatalf("Len: want %v; but got %v", len(keys), m.Len())
+	}
+	checkCounts(t, m.root)
+	i := 0
+	for key, val := range m.All() {
+		data := dataset[keys[i]]
+		if !key.Equal(data.Key) {
+			t.Fatalf("want %v; but got %v", data.Key, key)
+		}
+		if val != uint32(want[keys[i]]) {
+			t.Fatalf("want %v; but got %v", want[keys[i]], val)
+		}
+		i++
+	}
+}
+
+// mutate applies a random modification to m and the model want.
+func mutate(r *rand.Rand, m *Tree[uint32], dataset []TestData, want []int) {
+	i := r.IntN(len(dataset))
+	key := dataset[i].Key
+	v := r.IntN(1000)
+	switch r.IntN(8) {
+	case 0, 1:
+		m.Set(key, uint32(v))
+		want[i] = v
+	case 2:
+		m.Delete(key)
+		want[i] = -1
+	case 3:
+		m.Swap(key, uint32(v))
+		want[i] = v
+	case 4:
+		m.Update(key, func(old uint32, exists bool) (uint32, bool) {
+			return old + 1, exists
+		})
+		if want[i] >= 0 {
+			want[i]++
+		}
+	case 5:
+		if ptr := m.GetPtr(key); ptr != nil {
+			*ptr = uint32(v)
+			want[i] = v
+		}
+	case 6:
+		lo := Inclusive(key)
+		hi := Exclusive(dataset[min(i+r.IntN(8), len(dataset)-1)].Key)
+		m.DeleteRange(lo, hi)
+		for j, data := range dataset {
+			if inBounds(data.Key, lo, hi) {
+				want[j] = -1
+			}
+		}
+	case 7:
+		p := BitsKey(key.Data, max(key.Nbits-2, 0))
+		if m.CountPrefix(p) < 8 {
+			m.DeletePrefix(p)
+			for j, data := range dataset {
+				if data.Key.HasPrefix(p) {
+					want[j] = -1
+				}
+			}
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	// Counted, so that checkTree verifies the counts of the copies
+	m := NewCountedTree[uint32]()
+	want := make([]int, len(dataset))
+	for i := range want {
+		want[i] = -1
+	}
+	for _, p := range r.Perm(len(dataset))[:len(dataset)/2] {
+		m.Set(dataset[p].Key, dataset[p].Value)
+		want[p] = int(dataset[p].Value)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		c := m.Clone()
+		c.Set(dataset[0].Key, 1)
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+		if c.Len() != 1 {
+			t.Errorf("want %v; but got %v", 1, c.Len())
+		}
+	})
+	t.Run("independent", func(t *testing.T) {
+		trees := []*Tree[uint32]{m}
+		models := [][]int{want}
+		for range 100 {
+			// Clone a random tree
+			i := r.IntN(len(trees))
+			trees = append(trees, trees[i].Clone())
+			models = append(models, slices.Clone(models[i]))
+			// Modify random trees
+			for range 20 {
+				j := r.IntN(len(trees))
+				mutate(r, trees[j], dataset, models[j])
+			}
+			for j := range trees {
+				checkTree(t, trees[j], dataset, models[j])
+			}
+		}
+	})
+	t.Run("GetPtr", func(t *testing.T) {
+		var m Tree[uint32]
+		key := dataset[0].Key
+		m.Set(key, 1)
+		c := m.Clone()
+		*c.GetPtr(key) = 2
+		*m.GetOrInsertPtr(key) = 3
+		if val, _ := c.Get(key); val != 2 {
+			t.Errorf("want %v; but got %v", 2, val)
+		}
+		if val, _ := m.Get(key); val != 3 {
+			t.Errorf("want %v; but got %v", 3, val)
+		}
+	})
+}
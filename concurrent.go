@@ -0,0 +1,143 @@
+package critbit
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrentTree is a crit-bit tree that maps Keys to values of type V
+// and is safe for concurrent use by multiple goroutines.
+// The zero value of ConcurrentTree is an empty tree ready for use.
+//
+// Readers never block: they load the current version of the tree
+// atomically and work on that immutable snapshot. Writers are
+// serialized with each other; each write builds a new version by path
+// copying, sharing every untouched node with the previous version,
+// and publishes it atomically. A reader therefore always sees
+// a consistent state, either entirely before or entirely after a write.
+//
+// ConcurrentTree suits read-heavy workloads such as longest prefix
+// matching on routing tables.
+type ConcurrentTree[V any] struct {
+	mu   sync.Mutex                       // serializes writers
+	root atomic.Pointer[ImmutableTree[V]] // current version
+}
+
+// Snapshot returns the current version of the tree.
+// The snapshot is never affected by later writes.
+//
+// Time complexity: O(1).
+func (t *ConcurrentTree[V]) Snapshot() *ImmutableTree[V] {
+	if s := t.root.Load(); s != nil {
+		return s
+	}
+	return new(ImmutableTree[V])
+}
+
+// Len returns the number of key-value pairs in the tree.
+func (t *ConcurrentTree[V]) Len() int {
+	return t.Snapshot().Len()
+}
+
+// Get retrieves the value associated with the given key.
+// Returns the value and true if the key exists, or the zero value
+// of V and false if the key is not found.
+func (t *ConcurrentTree[V]) Get(key Key) (V, bool) {
+	return t.Snapshot().Get(key)
+}
+
+// Longest performs longest prefix matching on the entire tree.
+// See Tree.Longest.
+func (t *ConcurrentTree[V]) Longest(key Key) (V, bool) {
+	return t.Snapshot().Longest(key)
+}
+
+// LongestPrefix is like Longest but also returns the matched key.
+func (t *ConcurrentTree[V]) LongestPrefix(key Key) (Key, V, bool) {
+	return t.Snapshot().LongestPrefix(key)
+}
+
+// All returns an iterator over all key-value pairs in the tree
+// in lexicographical order of keys.
+// The iterator works on the snapshot taken when iteration starts,
+// so concurrent writes never affect it.
+func (t *ConcurrentTree[V]) All() iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for key, val := range t.Snapshot().All() {
+			if !yield(key, val) {
+				break
+			}
+		}
+	}
+}
+
+// Range returns an iterator over the key-value pairs whose keys lie
+// between lo and hi, in lexicographical order of keys.
+// Like All, it works on the snapshot taken when iteration starts.
+func (t *ConcurrentTree[V]) Range(lo, hi Bound) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for key, val := range t.Snapshot().Range(lo, hi) {
+			if !yield(key, val) {
+				break
+			}
+		}
+	}
+}
+
+// WithPrefix returns an iterator over the key-value pairs whose keys
+// have p as a prefix, in lexicographical order of keys.
+// Like All, it works on the snapshot taken when iteration starts.
+func (t *ConcurrentTree[V]) WithPrefix(p Key) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for key, val := range t.Snapshot().WithPrefix(p) {
+			if !yield(key, val) {
+				break
+			}
+		}
+	}
+}
+
+// Set inserts a key-value pair into the tree or updates the value
+// if the key already exists.
+func (t *ConcurrentTree[V]) Set(key Key, value V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.Store(t.Snapshot().Set(key, value))
+}
+
+// Delete removes the key-value pair with the given key from the tree.
+// If the key does not exist, Delete is a no-op.
+func (t *ConcurrentTree[V]) Delete(key Key) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.Store(t.Snapshot().Delete(key))
+}
+
+// Update performs a read-modify-write of the value for the key,
+// with the same semantics as Tree.Update. The update is atomic with
+// respect to other writers.
+func (t *ConcurrentTree[V]) Update(key Key, fn func(old V, exists bool) (V, bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.Store(t.Snapshot().Update(key, fn))
+}
+
+// Batch applies several modifications as a single write.
+// fn receives a mutable copy of the current version; the result is
+// published atomically when fn returns, so readers see either none or
+// all of the modifications. fn must not retain the tree.
+//
+// Example:
+//
+//	routes.Batch(func(t *critbit.Tree[string]) {
+//	    t.DeletePrefix(oldPrefix)
+//	    t.Set(newPrefix, gateway)
+//	})
+func (t *ConcurrentTree[V]) Batch(fn func(t *Tree[V])) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.Snapshot().Thaw()
+	fn(c)
+	t.root.Store(&ImmutableTree[V]{tree: *c})
+}
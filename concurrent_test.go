@@ -0,0 +1,126 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentTree(t *testing.T) {
+	N := 256
+	dataset := setupDataset(N)
+	var m ConcurrentTree[uint32]
+
+	t.Run("empty", func(t *testing.T) {
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+		if _, found := m.Get(dataset[0].Key); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+	})
+	t.Run("Set Get Delete", func(t *testing.T) {
+		for _, data := range dataset {
+			m.Set(data.Key, data.Value)
+		}
+		snapshot := m.Snapshot()
+		for _, data := range dataset {
+			m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+				return old + 1, exists
+			})
+		}
+		for _, data := range dataset {
+			val, found := m.Get(data.Key)
+			if !found {
+				t.Fatalf("%x not found", data.Key)
+			}
+			if val != data.Value+1 {
+				t.Errorf("want %v; but got %v", data.Value+1, val)
+			}
+			val, _ = snapshot.Get(data.Key)
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+		}
+		for _, data := range dataset {
+			m.Delete(data.Key)
+		}
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+		if snapshot.Len() != N {
+			t.Errorf("want %v; but got %v", N, snapshot.Len())
+		}
+	})
+}
+
+func TestConcurrentTreeStress(t *testing.T) {
+	N := 64
+	dataset := setupDataset(N * 2)
+	var m ConcurrentTree[uint32]
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Writers keep pairs of keys i and N+i equal, using batches
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 1))
+			for range 2000 {
+				i := r.IntN(N)
+				v := r.Uint32()
+				if r.IntN(4) == 0 {
+					m.Batch(func(t *Tree[uint32]) {
+						t.Delete(dataset[i].Key)
+						t.Delete(dataset[N+i].Key)
+					})
+					continue
+				}
+				m.Batch(func(t *Tree[uint32]) {
+					t.Set(dataset[i].Key, v)
+					t.Set(dataset[N+i].Key, v)
+				})
+			}
+		}()
+	}
+	// Readers verify every snapshot is consistent
+	var rg sync.WaitGroup
+	for range 4 {
+		rg.Add(1)
+		go func() {
+			defer rg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				snapshot := m.Snapshot()
+				n := 0
+				for i := range N {
+					a, afound := snapshot.Get(dataset[i].Key)
+					b, bfound := snapshot.Get(dataset[N+i].Key)
+					if afound != bfound || a != b {
+						t.Errorf("inconsistent snapshot at %v", i)
+						return
+					}
+					if afound {
+						n += 2
+					}
+				}
+				if snapshot.Len() != n {
+					t.Errorf("want %v; but got %v", n, snapshot.Len())
+					return
+				}
+				for key := range m.All() {
+					_ = key
+				}
+				_, _ = m.Longest(dataset[0].Key)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	rg.Wait()
+}
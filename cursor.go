@@ -0,0 +1,219 @@
+package critbit
+
+// Cursor is a position in a crit-bit tree that can move forward and
+// backward from any entry, like a database cursor.
+//
+// A cursor is either at an entry, at the key of an entry it has deleted,
+// or not positioned at all. A cursor that is not positioned moves to the
+// first entry on Next and to the last entry on Prev, and moving past
+// either end leaves it not positioned again.
+//
+// The cursor keeps the path of internal nodes from the root to its entry,
+// so that moving to the adjacent entry only climbs and descends the
+// nodes between the two. It remains usable when the tree is modified by
+// other means: the path is then rebuilt from the key at the cursor.
+type Cursor[V any] struct {
+	tree *Tree[V]
+	// internal nodes on the path from the root to leaf
+	path []*Inner[V]
+	leaf *Leaf[V] // entry at the cursor, nil if there is none
+	key  Key      // key at the cursor, kept after its entry is deleted
+	at   bool     // whether the cursor is positioned at key
+	mods int      // modifications of the tree when path was built
+}
+
+// Cursor returns a new Cursor over the tree, not positioned at any entry.
+//
+// Example:
+//
+//	c := tree.Cursor()
+//	for ok := c.First(); ok; ok = c.Next() {
+//	    if c.Value() == 0 {
+//	        c.Delete()
+//	    }
+//	}
+func (t *Tree[V]) Cursor() *Cursor[V] {
+	return &Cursor[V]{tree: t}
+}
+
+// First moves the cursor to the smallest key in the tree.
+// Returns false if the tree is empty.
+func (c *Cursor[V]) First() bool {
+	return c.move(c.tree.root.edge(0))
+}
+
+// Last moves the cursor to the largest key in the tree.
+// Returns false if the tree is empty.
+func (c *Cursor[V]) Last() bool {
+	return c.move(c.tree.root.edge(1))
+}
+
+// Seek moves the cursor to the smallest key in the tree that is greater
+// than or equal to key.
+// Returns false if there is no such key.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (c *Cursor[V]) Seek(key Key) bool {
+	return c.move(c.tree.root.nearest(key, 0, true))
+}
+
+// Next moves the cursor to the next key in lexicographical order.
+// Returns false if there is no next key.
+//
+// Time complexity: Amortized O(1) per call while the tree is not
+// modified, O(k) otherwise where k is the length of the key in bits.
+func (c *Cursor[V]) Next() bool {
+	return c.step(0)
+}
+
+// Prev moves the cursor to the previous key in lexicographical order.
+// Returns false if there is no previous key.
+//
+// Time complexity: Like Next.
+func (c *Cursor[V]) Prev() bool {
+	return c.step(1)
+}
+
+// Valid reports whether the cursor is at an entry of the tree.
+func (c *Cursor[V]) Valid() bool {
+	c.sync()
+	return c.leaf != nil
+}
+
+// Key returns the key at the cursor, which remains available after
+// the entry is deleted.
+// Returns an empty key if the cursor is not positioned.
+func (c *Cursor[V]) Key() Key {
+	return c.key
+}
+
+// Value returns the value of the entry at the cursor.
+// Returns the zero value of V if the cursor is not at an entry.
+func (c *Cursor[V]) Value() V {
+	c.sync()
+	if c.leaf == nil {
+		var zero V
+		return zero
+	}
+	return c.leaf.Value
+}
+
+// Set replaces the value of the entry at the cursor. If the entry has
+// been deleted, Set inserts it again. Set is a no-op if the cursor is
+// not positioned.
+func (c *Cursor[V]) Set(value V) {
+	if !c.at {
+		return
+	}
+	c.sync()
+	if c.leaf != nil && c.leaf.owner == c.tree.owner {
+		c.leaf.Value = value
+		return
+	}
+	c.tree.Set(c.key, value)
+}
+
+// Delete removes the entry at the cursor from the tree.
+// The cursor keeps the deleted key, so Next and Prev move to the entries
+// after and before it. Delete is a no-op if the cursor is not at an
+// entry.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (c *Cursor[V]) Delete() {
+	c.sync()
+	if c.leaf == nil {
+		return
+	}
+	var p *Inner[V]
+	var dir int
+	if len(c.path) > 0 {
+		p = c.path[len(c.path)-1]
+		dir = c.key.Direction(p.bit)
+	}
+	c.tree.detach(c.key, p, dir, 1)
+	c.leaf = nil
+}
+
+// step moves the cursor to the adjacent entry in the given traversal
+// direction: 0 for the next entry, 1 for the previous one.
+//
+// Without modifications, the entry is found from the path: it is the
+// first leaf of the other subtree at the deepest ancestor where the
+// path took the child in direction dir.
+// Internal method used by Next and Prev.
+func (c *Cursor[V]) step(dir int) bool {
+	if !c.at {
+		return c.move(c.tree.root.edge(dir))
+	}
+	c.sync()
+	if c.leaf == nil {
+		// The entry is gone, so find the neighbor of its key
+		return c.move(c.tree.root.nearest(c.key, dir, false))
+	}
+	for i := len(c.path) - 1; i >= 0; i-- {
+		inner := c.path[i]
+		if c.key.Direction(inner.bit) != dir {
+			continue
+		}
+		c.path = c.path[:i+1]
+		n := inner.child[dir^1]
+		for n.Inner != nil {
+			c.path = append(c.path, n.Inner)
+			n = n.Inner.child[dir]
+		}
+		c.leaf = n.Leaf
+		c.key = n.Leaf.Key
+		return true
+	}
+	c.reset()
+	return false
+}
+
+// move positions the cursor at leaf and builds the path to it.
+// A nil leaf leaves the cursor not positioned.
+// Returns whether the cursor is at an entry.
+func (c *Cursor[V]) move(leaf *Leaf[V]) bool {
+	if leaf == nil {
+		c.reset()
+		return false
+	}
+	c.at = true
+	c.key = leaf.Key
+	c.locate()
+	return true
+}
+
+// sync rebuilds the path to the key at the cursor if the tree has been
+// modified since it was built, as the nodes on the path may have been
+// replaced or removed.
+func (c *Cursor[V]) sync() {
+	if c.at && c.mods != c.tree.mods {
+		c.locate()
+	}
+}
+
+// locate builds the path from the root following the key at the cursor
+// and sets the entry at the cursor to the leaf with that key, or nil
+// if there is none.
+func (c *Cursor[V]) locate() {
+	c.path = c.path[:0]
+	n := c.tree.root
+	for n.Inner != nil {
+		c.path = append(c.path, n.Inner)
+		n = n.Inner.child[c.key.Direction(n.Inner.bit)]
+	}
+	c.leaf = n.Leaf
+	if c.leaf != nil && !c.leaf.Key.Equal(c.key) {
+		c.leaf = nil
+	}
+	c.mods = c.tree.mods
+}
+
+// reset leaves the cursor not positioned.
+func (c *Cursor[V]) reset() {
+	c.path = c.path[:0]
+	c.leaf = nil
+	c.key = Key{}
+	c.at = false
+	c.mods = c.tree.mods
+}
@@ -0,0 +1,177 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// nextIndex returns the index of the first key present in the model
+// want after index i in the given traversal direction, or -1.
+func nextIndex(want []int, i, dir int) int {
+	step := 1 - 2*dir
+	for j := i + step; j >= 0 && j < len(want); j += step {
+		if want[j] >= 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+func TestCursor(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		c := m.Cursor()
+		if c.First() || c.Last() || c.Next() || c.Prev() || c.Seek(Key{}) {
+			t.Errorf("want %v; but got %v", false, true)
+		}
+		if c.Valid() {
+			t.Errorf("want %v; but got %v", false, true)
+		}
+		c.Set(1)
+		c.Delete()
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+	})
+	t.Run("Next", func(t *testing.T) {
+		c := m.Cursor()
+		i := 0
+		for ok := c.First(); ok; ok = c.Next() {
+			data := dataset[i]
+			if !c.Key().Equal(data.Key) {
+				t.Fatalf("want %v; but got %v", data.Key, c.Key())
+			}
+			if c.Value() != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, c.Value())
+			}
+			i++
+		}
+		if i != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), i)
+		}
+		// Past the end, the cursor starts over
+		if !c.Next() || !c.Key().Equal(dataset[0].Key) {
+			t.Errorf("want %v; but got %v", dataset[0].Key, c.Key())
+		}
+	})
+	t.Run("Prev", func(t *testing.T) {
+		c := m.Cursor()
+		i := len(dataset) - 1
+		for ok := c.Last(); ok; ok = c.Prev() {
+			data := dataset[i]
+			if !c.Key().Equal(data.Key) {
+				t.Fatalf("want %v; but got %v", data.Key, c.Key())
+			}
+			i--
+		}
+		if i != -1 {
+			t.Errorf("want %v; but got %v", -1, i)
+		}
+		if !c.Prev() || !c.Key().Equal(dataset[len(dataset)-1].Key) {
+			t.Errorf("want %v; but got %v", dataset[len(dataset)-1].Key, c.Key())
+		}
+	})
+	t.Run("Seek", func(t *testing.T) {
+		c := m.Cursor()
+		for range 1000 {
+			key := randomBound(r, dataset).key
+			i, _ := slices.BinarySearchFunc(dataset, key, func(data TestData, key Key) int {
+				return data.Key.Compare(key)
+			})
+			ok := c.Seek(key)
+			if i == len(dataset) {
+				if ok {
+					t.Fatalf("Seek(%v): want none; but got %v", key, c.Key())
+				}
+				continue
+			}
+			if !ok || !c.Key().Equal(dataset[i].Key) {
+				t.Fatalf("Seek(%v): want %v; but got %v", key, dataset[i].Key, c.Key())
+			}
+			// Walk both ways from the position
+			for j := i; j > max(i-4, 0); j-- {
+				if !c.Prev() || !c.Key().Equal(dataset[j-1].Key) {
+					t.Fatalf("want %v; but got %v", dataset[j-1].Key, c.Key())
+				}
+			}
+		}
+	})
+	t.Run("Set Delete", func(t *testing.T) {
+		m := m.Clone()
+		want := make([]int, len(dataset))
+		for i, data := range dataset {
+			want[i] = int(data.Value)
+		}
+		c := m.Cursor()
+		i := -1
+		for range 5000 {
+			dir := r.IntN(2)
+			if i < 0 {
+				// Not positioned: starts from either end
+				if dir == 0 {
+					i = nextIndex(want, -1, 0)
+				} else {
+					i = nextIndex(want, len(want), 1)
+				}
+			} else {
+				i = nextIndex(want, i, dir)
+			}
+			ok := c.step(dir)
+			if ok != (i >= 0) {
+				t.Fatalf("want %v; but got %v", i >= 0, ok)
+			}
+			if !ok {
+				continue
+			}
+			if !c.Key().Equal(dataset[i].Key) {
+				t.Fatalf("want %v; but got %v", dataset[i].Key, c.Key())
+			}
+			if c.Value() != uint32(want[i]) {
+				t.Fatalf("want %v; but got %v", want[i], c.Value())
+			}
+			switch r.IntN(4) {
+			case 0:
+				c.Delete()
+				want[i] = -1
+				if c.Valid() {
+					t.Errorf("want %v; but got %v", false, true)
+				}
+				if !c.Key().Equal(dataset[i].Key) {
+					t.Errorf("want %v; but got %v", dataset[i].Key, c.Key())
+				}
+				if r.IntN(4) == 0 {
+					// Reinsert the deleted entry
+					c.Set(7)
+					want[i] = 7
+				}
+			case 1:
+				v := r.IntN(1000)
+				c.Set(uint32(v))
+				want[i] = v
+			case 2:
+				// Modify the tree behind the cursor
+				mutate(r, m, dataset, want)
+				if r.IntN(8) == 0 {
+					m.Clone()
+				}
+			}
+			if r.IntN(256) == 0 {
+				for j, data := range dataset {
+					if want[j] < 0 && r.IntN(2) == 0 {
+						m.Set(data.Key, data.Value)
+						want[j] = int(data.Value)
+					}
+				}
+			}
+		}
+		checkTree(t, m, dataset, want)
+	})
+}
@@ -0,0 +1,176 @@
+package critbit
+
+import (
+	"iter"
+)
+
+// ImmutableTree is a persistent crit-bit tree that maps Keys to values
+// of type V. The zero value of ImmutableTree is an empty tree ready for
+// use.
+//
+// An ImmutableTree is never modified. Set, Delete and the other update
+// methods return a new tree instead, which shares every node off the
+// modified path with the original. Keeping many versions is therefore
+// cheap, which suits undo histories and queries on past states.
+//
+// ImmutableTree is safe for concurrent reads, since no method modifies
+// the receiver.
+type ImmutableTree[V any] struct {
+	tree Tree[V]
+}
+
+// Freeze returns an immutable copy of the tree.
+// Like Clone, it runs in O(1) time and the nodes are shared until
+// the tree modifies them.
+//
+// As with Clone, pointers previously returned by GetPtr and
+// GetOrInsertPtr on t must not be written through afterwards: they
+// refer to leaves shared with the frozen copy, which would change too.
+func (t *Tree[V]) Freeze() *ImmutableTree[V] {
+	return &ImmutableTree[V]{tree: *t.Clone()}
+}
+
+// Thaw returns a mutable copy of the tree, sharing all nodes with it.
+// It is useful for applying many updates at once before freezing the
+// result again.
+//
+// Time complexity: O(1).
+func (t *ImmutableTree[V]) Thaw() *Tree[V] {
+	return t.tree.fork()
+}
+
+// Len returns the number of key-value pairs in the tree.
+func (t *ImmutableTree[V]) Len() int {
+	return t.tree.Len()
+}
+
+// Get retrieves the value associated with the given key.
+// Returns the value and true if the key exists, or the zero value
+// of V and false if the key is not found.
+func (t *ImmutableTree[V]) Get(key Key) (V, bool) {
+	return t.tree.Get(key)
+}
+
+// Set returns a new tree in which the key is associated with value.
+// Only the nodes on the path to the key are copied.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *ImmutableTree[V]) Set(key Key, value V) *ImmutableTree[V] {
+	c := t.tree.fork()
+	c.Set(key, value)
+	return &ImmutableTree[V]{tree: *c}
+}
+
+// Delete returns a new tree without the key.
+// If the key does not exist, Delete returns the receiver itself.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *ImmutableTree[V]) Delete(key Key) *ImmutableTree[V] {
+	c := t.tree.fork()
+	if _, found := c.LoadAndDelete(key); !found {
+		return t
+	}
+	return &ImmutableTree[V]{tree: *c}
+}
+
+// DeletePrefix returns a new tree without the keys that have p as
+// a prefix, and the number of removed keys.
+// If no key has the prefix, it returns the receiver itself.
+func (t *ImmutableTree[V]) DeletePrefix(p Key) (*ImmutableTree[V], int) {
+	c := t.tree.fork()
+	m := c.DeletePrefix(p)
+	if m == 0 {
+		return t, 0
+	}
+	return &ImmutableTree[V]{tree: *c}, m
+}
+
+// Update returns a new tree with the value for the key updated by fn,
+// with the same semantics as Tree.Update.
+func (t *ImmutableTree[V]) Update(key Key, fn func(old V, exists bool) (V, bool)) *ImmutableTree[V] {
+	c := t.tree.fork()
+	c.Update(key, fn)
+	return &ImmutableTree[V]{tree: *c}
+}
+
+// Longest performs longest prefix matching on the entire tree.
+// See Tree.Longest.
+func (t *ImmutableTree[V]) Longest(key Key) (V, bool) {
+	return t.tree.Longest(key)
+}
+
+// LongestPrefix is like Longest but also returns the matched key.
+func (t *ImmutableTree[V]) LongestPrefix(key Key) (Key, V, bool) {
+	return t.tree.LongestPrefix(key)
+}
+
+// Prefixes returns an iterator over the key-value pairs whose keys are
+// prefixes of the given key, from the shortest to the longest.
+func (t *ImmutableTree[V]) Prefixes(key Key) iter.Seq2[Key, V] {
+	return t.tree.Prefixes(key)
+}
+
+// Min returns the smallest key in the tree and its value.
+func (t *ImmutableTree[V]) Min() (Key, V, bool) {
+	return t.tree.Min()
+}
+
+// Max returns the largest key in the tree and its value.
+func (t *ImmutableTree[V]) Max() (Key, V, bool) {
+	return t.tree.Max()
+}
+
+// Ceiling returns the smallest key in the tree that is greater than
+// or equal to key, and its value.
+func (t *ImmutableTree[V]) Ceiling(key Key) (Key, V, bool) {
+	return t.tree.Ceiling(key)
+}
+
+// Floor returns the largest key in the tree that is less than
+// or equal to key, and its value.
+func (t *ImmutableTree[V]) Floor(key Key) (Key, V, bool) {
+	return t.tree.Floor(key)
+}
+
+// All returns an iterator over all key-value pairs in the tree
+// in lexicographical order of keys.
+func (t *ImmutableTree[V]) All() iter.Seq2[Key, V] {
+	return t.tree.All()
+}
+
+// Keys returns an iterator over all keys in the tree
+// in lexicographical order.
+func (t *ImmutableTree[V]) Keys() iter.Seq[Key] {
+	return t.tree.Keys()
+}
+
+// Values returns an iterator over all values in the tree in the order
+// corresponding to their keys' lexicographical order.
+func (t *ImmutableTree[V]) Values() iter.Seq[V] {
+	return t.tree.Values()
+}
+
+// Backward returns an iterator over all key-value pairs in the tree
+// in reverse lexicographical order of keys.
+func (t *ImmutableTree[V]) Backward() iter.Seq2[Key, V] {
+	return t.tree.Backward()
+}
+
+// Range returns an iterator over the key-value pairs whose keys lie
+// between lo and hi, in lexicographical order of keys.
+func (t *ImmutableTree[V]) Range(lo, hi Bound) iter.Seq2[Key, V] {
+	return t.tree.Range(lo, hi)
+}
+
+// WithPrefix returns an iterator over the key-value pairs whose keys
+// have p as a prefix, in lexicographical order of keys.
+func (t *ImmutableTree[V]) WithPrefix(p Key) iter.Seq2[Key, V] {
+	return t.tree.WithPrefix(p)
+}
+
+// Scanner returns a new Scanner over the tree.
+// If reverse is true, the scanner will traverse in reverse
+// lexicographical order.
+func (t *ImmutableTree[V]) Scanner(reverse bool) *Scanner[V] {
+	return t.tree.Scanner(reverse)
+}
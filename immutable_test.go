@@ -0,0 +1,98 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestImmutableTree(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 256)
+
+	// versions[i] holds the values of version i indexed like dataset,
+	// with -1 for absent keys
+	var versions [][]int
+	var trees []*ImmutableTree[uint32]
+	want := make([]int, len(dataset))
+	for i := range want {
+		want[i] = -1
+	}
+	tree := new(ImmutableTree[uint32])
+	t.Run("Set Delete", func(t *testing.T) {
+		for range 1000 {
+			i := r.IntN(len(dataset))
+			data := dataset[i]
+			if r.IntN(3) == 0 {
+				next := tree.Delete(data.Key)
+				if want[i] < 0 && next != tree {
+					t.Errorf("Delete(%v) of absent key returned a new tree", data.Key)
+				}
+				tree = next
+				want[i] = -1
+			} else {
+				v := r.IntN(1000)
+				tree = tree.Set(data.Key, uint32(v))
+				want[i] = v
+			}
+			trees = append(trees, tree)
+			versions = append(versions, slices.Clone(want))
+		}
+	})
+	t.Run("versions", func(t *testing.T) {
+		for i, tree := range trees {
+			checkTree(t, &tree.tree, dataset, versions[i])
+		}
+	})
+	t.Run("Update", func(t *testing.T) {
+		prev := trees[len(trees)-1]
+		next := prev
+		for i := range dataset {
+			next = next.Update(dataset[i].Key, func(old uint32, exists bool) (uint32, bool) {
+				return old + 1, true
+			})
+		}
+		checkTree(t, &prev.tree, dataset, versions[len(versions)-1])
+		if next.Len() != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), next.Len())
+		}
+	})
+	t.Run("DeletePrefix", func(t *testing.T) {
+		prev := trees[len(trees)-1]
+		next, m := prev.DeletePrefix(Key{})
+		if m != prev.Len() {
+			t.Errorf("want %v; but got %v", prev.Len(), m)
+		}
+		if next.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, next.Len())
+		}
+		checkTree(t, &prev.tree, dataset, versions[len(versions)-1])
+		if again, m := next.DeletePrefix(Key{}); again != next || m != 0 {
+			t.Errorf("DeletePrefix of empty tree returned a new tree")
+		}
+	})
+	t.Run("Freeze Thaw", func(t *testing.T) {
+		var m Tree[uint32]
+		for _, data := range dataset {
+			m.Set(data.Key, data.Value)
+		}
+		frozen := m.Freeze()
+		m.DeletePrefix(Key{})
+		if frozen.Len() != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), frozen.Len())
+		}
+		thawed := frozen.Thaw()
+		for _, data := range dataset {
+			thawed.Set(data.Key, 0)
+		}
+		for i, data := range dataset {
+			val, found := frozen.Get(data.Key)
+			if !found {
+				t.Fatalf("%x not found", data.Key)
+			}
+			if val != uint32(i) {
+				t.Errorf("want %v; but got %v", i, val)
+			}
+		}
+	})
+}
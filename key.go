@@ -98,7 +98,7 @@ func (k Key) Critbit(b Key) int {
 	boff := b.Nbits >> 3
 
 	var moff, mod int
-	if koff < boff {
+	if k.Nbits < b.Nbits {
 		moff = koff
 		mod = k.Nbits & 7
 	} else {
@@ -196,3 +196,20 @@ func (k Key) HasPrefix(p Key) bool {
 	}
 	return true
 }
+
+// Compare returns an integer comparing two keys in the order used by
+// the crit-bit tree.
+// The result will be 0 if k == b, -1 if k < b, and +1 if k > b.
+//
+// Keys are ordered bit by bit from the most significant bit of the
+// first byte. A key that is a prefix of another key sorts before it.
+func (k Key) Compare(b Key) int {
+	bit := k.Critbit(b)
+	if bit == -1 {
+		return 0
+	}
+	if k.Direction(bit) == 0 {
+		return -1
+	}
+	return 1
+}
@@ -131,6 +131,18 @@ func TestKey_Critbit(t *testing.T) {
 			b:    BitsKey([]byte{0b1000_0000, 0}, 10),
 			bit:  4,
 		},
+		{
+			name: "same byte diff nbit shorter first",
+			k:    BitsKey([]byte{0b0000_0000}, 1),
+			b:    BitsKey([]byte{0b0100_0000}, 2),
+			bit:  2,
+		},
+		{
+			name: "same byte diff nbit shorter second",
+			k:    BitsKey([]byte{0b0100_0000}, 2),
+			b:    BitsKey([]byte{0b0000_0000}, 1),
+			bit:  2,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -141,3 +153,47 @@ func TestKey_Critbit(t *testing.T) {
 		})
 	}
 }
+
+// bitString returns the significant bits of k as a string of '0' and '1'.
+func bitString(k Key) string {
+	b := make([]byte, k.Nbits)
+	for i := range b {
+		b[i] = '0' + k.Data[i>>3]>>(7-i&7)&1
+	}
+	return string(b)
+}
+
+func TestKey_Compare(t *testing.T) {
+	keys := []Key{
+		{},
+		BitsKey([]byte{0b0000_0000}, 1),
+		BitsKey([]byte{0b0000_0000}, 2),
+		BitsKey([]byte{0b0100_0000}, 2),
+		BitsKey([]byte{0b1000_0000}, 1),
+		BitsKey([]byte{0b1000_0000}, 7),
+		BitsKey([]byte{0b1000_0000}, 8),
+		BitsKey([]byte{0b1000_0000, 0}, 9),
+		BitsKey([]byte{0b1000_0000, 0b1000_0000}, 9),
+		BitsKey([]byte{0b1000_0001}, 8),
+		StringKey("a"),
+		StringKey("ab"),
+		StringKey("b"),
+		Uint32Key(0),
+		Uint32Key(1),
+		Uint32Key(1 << 31),
+	}
+	for _, a := range keys {
+		for _, b := range keys {
+			want := 0
+			if sa, sb := bitString(a), bitString(b); sa < sb {
+				want = -1
+			} else if sa > sb {
+				want = 1
+			}
+			got := a.Compare(b)
+			if got != want {
+				t.Errorf("%v.Compare(%v): want %v; but got %v", a, b, want, got)
+			}
+		}
+	}
+}
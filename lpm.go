@@ -0,0 +1,232 @@
+package critbit
+
+import (
+	"iter"
+)
+
+// longest returns the leaf holding the longest key in the subtree that
+// is a prefix of the given key, or nil if there is none.
+//
+// A stored key that prefixes other stored keys sorts before all of
+// them, so it is always the left leaf of the internal node whose
+// critical bit is its own length. Only one prefix of key can have no
+// longer key extending it, and that one is the leaf key leads to.
+// longest therefore checks the leaf at the end of the path first, and
+// otherwise takes the deepest such left leaf on the path above the
+// bit where key diverges from that leaf. No subtree off the path is
+// ever visited, so the lookup is bounded by the depth of the path
+// rather than by the number of non-matching leaves.
+func (n Node[V]) longest(key Key) *Leaf[V] {
+	leaf := n.find(key)
+	if leaf == nil {
+		return nil
+	}
+	if key.HasPrefix(leaf.Key) {
+		return leaf
+	}
+	bit := leaf.Key.Critbit(key)
+	var match *Leaf[V]
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Keys below the divergence no longer share key's bits
+		if inner.bit > bit {
+			break
+		}
+		dir := key.Direction(inner.bit)
+		if dir == 1 && inner.bit&1 == 0 {
+			// Length critical bit: the left leaf is a prefix of key
+			if left := inner.child[0].Leaf; left != nil {
+				match = left
+			}
+		}
+		n = inner.child[dir]
+	}
+	return match
+}
+
+// prefixes returns an iterator over the leaves in the subtree whose keys
+// are prefixes of the given key, from the shortest to the longest.
+//
+// It yields the same candidates as longest, in the order they appear
+// on the path.
+func (n Node[V]) prefixes(key Key) iter.Seq[*Leaf[V]] {
+	return func(yield func(*Leaf[V]) bool) {
+		leaf := n.find(key)
+		if leaf == nil {
+			return
+		}
+		bit := leaf.Key.Critbit(key)
+		for {
+			inner := n.Inner
+			if inner == nil {
+				break
+			}
+			// Keys below the divergence no longer share key's bits
+			if bit != -1 && inner.bit > bit {
+				break
+			}
+			dir := key.Direction(inner.bit)
+			if dir == 1 && inner.bit&1 == 0 {
+				// Length critical bit: the left leaf is a prefix of key
+				if left := inner.child[0].Leaf; left != nil {
+					if !yield(left) {
+						return
+					}
+				}
+			}
+			n = inner.child[dir]
+		}
+		if key.HasPrefix(leaf.Key) {
+			yield(leaf)
+		}
+	}
+}
+
+// prefixes is like Node.prefixes over the whole tree, but the loop body
+// may modify the tree. If it does, the walk starts over from the root
+// of the modified tree and skips the prefixes up to the last one
+// yielded, rather than following stale nodes.
+func (t *Tree[V]) prefixes(key Key) iter.Seq[*Leaf[V]] {
+	return func(yield func(*Leaf[V]) bool) {
+		last := -1 // length of the last prefix yielded
+		for {
+			mods := t.mods
+			for leaf := range t.root.prefixes(key) {
+				if leaf.Key.Nbits <= last {
+					continue
+				}
+				if !yield(leaf) {
+					return
+				}
+				last = leaf.Key.Nbits
+				if t.mods != mods {
+					break
+				}
+			}
+			if t.mods == mods {
+				return
+			}
+		}
+	}
+}
+
+// Shortest performs shortest prefix matching on the entire tree.
+// It finds the shortest key in the tree that is a prefix of the given
+// key, such as the least specific route covering a destination.
+//
+// Returns the shortest matching prefix, its value and true,
+// or false if no prefix match is found.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Shortest(key Key) (Key, V, bool) {
+	for leaf := range t.root.prefixes(key) {
+		return leafEntry(leaf)
+	}
+	return leafEntry[V](nil)
+}
+
+// Prefixes returns an iterator over the key-value pairs whose keys are
+// prefixes of the given key, from the shortest to the longest.
+// The last pair yielded is the longest prefix match.
+//
+// This is useful for hierarchical lookups such as ACL evaluation or
+// configuration inheritance, where every matching level applies.
+//
+// Example:
+//
+//	for prefix, value := range tree.Prefixes(key) {
+//	    fmt.Printf("Prefix: %v, Value: %v\n", prefix, value)
+//	}
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Prefixes(key Key) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for leaf := range t.prefixes(key) {
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// PrefixesBackward returns an iterator over the key-value pairs whose
+// keys are prefixes of the given key, from the longest to the shortest.
+// The first pair yielded is the longest prefix match.
+//
+// The prefixes are collected before the first one is yielded. If the
+// loop body modifies the tree, the remaining shorter prefixes are
+// collected again from the modified tree.
+func (t *Tree[V]) PrefixesBackward(key Key) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		var leaves []*Leaf[V]
+		for leaf := range t.root.prefixes(key) {
+			leaves = append(leaves, leaf)
+		}
+		mods := t.mods
+		for i := len(leaves) - 1; i >= 0; i-- {
+			leaf := leaves[i]
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+			if t.mods != mods {
+				mods = t.mods
+				leaves = leaves[:0]
+				for p := range t.root.prefixes(key) {
+					if p.Key.Nbits >= leaf.Key.Nbits {
+						break
+					}
+					leaves = append(leaves, p)
+				}
+				i = len(leaves)
+			}
+		}
+	}
+}
+
+// Overlapping returns an iterator over the key-value pairs whose keys
+// overlap the prefix p: the keys that are prefixes of p, covering it,
+// and the keys that have p as a prefix, covered by it.
+// The pairs are yielded in lexicographical order of keys, so the
+// covering prefixes come first, from the shortest to the longest.
+//
+// It combines the path walk of Longest with an enumeration of the
+// subtree below p.
+//
+// Time complexity: O(k + m) where k is the length of p in bits and m
+// is the number of key-value pairs yielded.
+func (t *Tree[V]) Overlapping(p Key) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for leaf := range t.prefixes(p) {
+			if leaf.Key.Nbits == p.Nbits {
+				// p itself comes with the subtree below
+				break
+			}
+			if !yield(leaf.Key, leaf.Value) {
+				return
+			}
+		}
+		for key, val := range t.WithPrefix(p) {
+			if !yield(key, val) {
+				break
+			}
+		}
+	}
+}
+
+// Overlaps reports whether any key in the tree overlaps the prefix p,
+// that is, whether a key is a prefix of p or has p as a prefix.
+// It is a cheap admission check for Overlapping.
+//
+// Time complexity: O(k) where k is the length of p in bits.
+func (t *Tree[V]) Overlaps(p Key) bool {
+	if !t.root.prefix(p).empty() {
+		return true
+	}
+	for range t.root.prefixes(p) {
+		return true
+	}
+	return false
+}
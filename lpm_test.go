@@ -1,7 +1,9 @@
 package critbit
 
 import (
+	"math/rand/v2"
 	"net/netip"
+	"slices"
 	"testing"
 )
 
@@ -95,5 +97,189 @@ func TestLongest(t *testing.T) {
 				t.Errorf("want %v; but got %v", tc.v, got)
 			}
 		})
+		t.Run("LongestPrefix "+tc.name, func(t *testing.T) {
+			key := Key{
+				Data:  tc.prefix.Addr().AsSlice(),
+				Nbits: tc.prefix.Bits(),
+			}
+			k, got, found := m.LongestPrefix(key)
+			if found != tc.found {
+				t.Errorf("want %v; but got %v", tc.found, found)
+			}
+			if got != tc.v {
+				t.Errorf("want %v; but got %v", tc.v, got)
+			}
+			if !found {
+				return
+			}
+			p := netip.MustParsePrefix(addrs[tc.v])
+			want := Key{
+				Data:  p.Addr().AsSlice(),
+				Nbits: p.Bits(),
+			}
+			if !k.Equal(want) {
+				t.Errorf("want %v; but got %v", want, k)
+			}
+		})
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	for range 1000 {
+		key := randomBound(r, dataset).key
+		// dataset is sorted, so shorter prefixes come first
+		var want []uint32
+		for _, data := range dataset {
+			if key.HasPrefix(data.Key) {
+				want = append(want, data.Value)
+			}
+		}
+		var got []uint32
+		for _, val := range m.Prefixes(key) {
+			got = append(got, val)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("Prefixes(%v): want %v; but got %v", key, want, got)
+		}
+		got = got[:0]
+		for _, val := range m.PrefixesBackward(key) {
+			got = append(got, val)
+		}
+		slices.Reverse(want)
+		if !slices.Equal(got, want) {
+			t.Fatalf("PrefixesBackward(%v): want %v; but got %v", key, want, got)
+		}
+	}
+	t.Run("Shortest", func(t *testing.T) {
+		for range 1000 {
+			key := randomBound(r, dataset).key
+			var want *TestData
+			for i, data := range dataset {
+				if key.HasPrefix(data.Key) {
+					want = &dataset[i]
+					break
+				}
+			}
+			got, val, found := m.Shortest(key)
+			if found != (want != nil) {
+				t.Fatalf("Shortest(%v): want %v; but got %v", key, want != nil, found)
+			}
+			if !found {
+				continue
+			}
+			if !got.Equal(want.Key) {
+				t.Errorf("Shortest(%v): want %v; but got %v", key, want.Key, got)
+			}
+			if val != want.Value {
+				t.Errorf("want %v; but got %v", want.Value, val)
+			}
+		}
+	})
+	t.Run("Prefixes break", func(t *testing.T) {
+		key := Key{Data: []byte{0xff, 0xff}, Nbits: 16}
+		for range m.Prefixes(key) {
+			break
+		}
+		for range m.PrefixesBackward(key) {
+			break
+		}
+	})
+}
+
+// longestRecursive is the former backtracking implementation of
+// longest prefix matching, kept as a reference for tests and benchmarks.
+// It may visit whole subtrees that hold no prefix of key.
+func longestRecursive[V any](n Node[V], key Key) *Leaf[V] {
+	if inner := n.Inner; inner != nil {
+		dir := key.Direction(inner.bit)
+		leaf := longestRecursive(inner.child[dir], key)
+		if leaf != nil {
+			return leaf
+		}
+		if dir == 1 {
+			return longestRecursive(inner.child[0], key)
+		}
+	} else if leaf := n.Leaf; leaf != nil {
+		if key.HasPrefix(leaf.Key) {
+			return leaf
+		}
 	}
+	return nil
+}
+
+func TestLongestRoutes(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	for _, family := range []struct {
+		name  string
+		bytes int
+		dist  []routeLen
+	}{
+		{name: "IPv4", bytes: 4, dist: ipv4Dist},
+		{name: "IPv6", bytes: 16, dist: ipv6Dist},
+	} {
+		t.Run(family.name, func(t *testing.T) {
+			var m Tree[int]
+			routes := setupRoutes(r, 4096, family.bytes, family.dist)
+			for i, key := range routes {
+				m.Set(key, i)
+			}
+			for _, key := range setupQueries(r, 4096, family.bytes, routes) {
+				want := longestRecursive(m.root, key)
+				k, _, found := m.LongestPrefix(key)
+				if found != (want != nil) {
+					t.Fatalf("LongestPrefix(%v): want %v; but got %v", key, want != nil, found)
+				}
+				if found && !k.Equal(want.Key) {
+					t.Errorf("LongestPrefix(%v): want %v; but got %v", key, want.Key, k)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlapping(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	for range 1000 {
+		p := randomBound(r, dataset).key
+		var want []uint32
+		for _, data := range dataset {
+			if p.HasPrefix(data.Key) || data.Key.HasPrefix(p) {
+				want = append(want, data.Value)
+			}
+		}
+		var got []uint32
+		for _, val := range m.Overlapping(p) {
+			got = append(got, val)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("Overlapping(%v): want %v; but got %v", p, want, got)
+		}
+		if overlaps := m.Overlaps(p); overlaps != (len(want) > 0) {
+			t.Errorf("Overlaps(%v): want %v; but got %v", p, len(want) > 0, overlaps)
+		}
+	}
+	t.Run("Overlapping break", func(t *testing.T) {
+		for range m.Overlapping(Key{}) {
+			break
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		if m.Overlaps(Key{}) {
+			t.Errorf("want %v; but got %v", false, true)
+		}
+	})
 }
@@ -0,0 +1,244 @@
+package critbit
+
+// edge returns the first leaf of the subtree in the given traversal
+// direction: the leftmost leaf for 0, the rightmost leaf for 1.
+// Returns nil if the subtree is empty.
+func (n Node[V]) edge(dir int) *Leaf[V] {
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		n = inner.child[dir]
+	}
+	return n.Leaf
+}
+
+// Min returns the smallest key in the tree and its value.
+// Returns false if the tree is empty.
+//
+// Time complexity: O(d) where d is the depth of the tree.
+func (t *Tree[V]) Min() (Key, V, bool) {
+	return leafEntry(t.root.edge(0))
+}
+
+// Max returns the largest key in the tree and its value.
+// Returns false if the tree is empty.
+//
+// Time complexity: O(d) where d is the depth of the tree.
+func (t *Tree[V]) Max() (Key, V, bool) {
+	return leafEntry(t.root.edge(1))
+}
+
+// PopMin removes the smallest key from the tree and returns it
+// with its value.
+// Returns false if the tree is empty.
+func (t *Tree[V]) PopMin() (Key, V, bool) {
+	return t.pop(0)
+}
+
+// PopMax removes the largest key from the tree and returns it
+// with its value.
+// Returns false if the tree is empty.
+func (t *Tree[V]) PopMax() (Key, V, bool) {
+	return t.pop(1)
+}
+
+// pop removes the first leaf in the given traversal direction.
+func (t *Tree[V]) pop(dir int) (Key, V, bool) {
+	leaf := t.root.edge(dir)
+	if leaf != nil {
+		t.Delete(leaf.Key)
+	}
+	return leafEntry(leaf)
+}
+
+// leafEntry returns the key and value stored in leaf.
+// Returns false if leaf is nil.
+func leafEntry[V any](leaf *Leaf[V]) (Key, V, bool) {
+	if leaf == nil {
+		var val V
+		return Key{}, val, false
+	}
+	return leaf.Key, leaf.Value, true
+}
+
+// nearest returns the first leaf at or after key in the given traversal
+// direction: the smallest leaf >= key for 0, the largest leaf <= key
+// for 1. If inclusive is false, a leaf equal to key is skipped.
+// Returns nil if there is no such leaf.
+//
+// Like Set, it first locates the leaf sharing the longest common
+// prefix with key and computes their critical bit, then follows key
+// down to the node where key diverges from the tree. The last subtree
+// left behind on the far side of the path holds the answer when
+// the divergent subtree does not.
+func (n Node[V]) nearest(key Key, dir int, inclusive bool) *Leaf[V] {
+	leaf := n.find(key)
+	if leaf == nil {
+		return nil
+	}
+	bit := leaf.Key.Critbit(key)
+	var next Node[V] // nearest subtree after the path
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Stop at the subtree where key diverges
+		if bit != -1 && inner.bit > bit {
+			break
+		}
+		d := key.Direction(inner.bit)
+		if d == dir {
+			next = inner.child[d^1]
+		}
+		n = inner.child[d]
+	}
+	if bit == -1 {
+		// n is the leaf equal to key
+		if inclusive {
+			return n.Leaf
+		}
+	} else if key.Direction(bit) == dir {
+		// All keys in n come after key
+		return n.edge(dir)
+	}
+	return next.edge(dir)
+}
+
+// Ceiling returns the smallest key in the tree that is greater than
+// or equal to key, and its value.
+// Returns false if there is no such key.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Ceiling(key Key) (Key, V, bool) {
+	return leafEntry(t.root.nearest(key, 0, true))
+}
+
+// Higher returns the smallest key in the tree that is strictly
+// greater than key, and its value.
+// Returns false if there is no such key.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Higher(key Key) (Key, V, bool) {
+	return leafEntry(t.root.nearest(key, 0, false))
+}
+
+// Floor returns the largest key in the tree that is less than
+// or equal to key, and its value.
+// Returns false if there is no such key.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Floor(key Key) (Key, V, bool) {
+	return leafEntry(t.root.nearest(key, 1, true))
+}
+
+// Lower returns the largest key in the tree that is strictly
+// less than key, and its value.
+// Returns false if there is no such key.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Lower(key Key) (Key, V, bool) {
+	return leafEntry(t.root.nearest(key, 1, false))
+}
+
+// rank returns the number of leaves in the subtree whose keys are
+// less than key, or less than or equal to key if inclusive is true.
+//
+// It follows the same walk as nearest, adding up the leaf counts of
+// the subtrees left behind before the path.
+func (n Node[V]) rank(key Key, inclusive bool) int {
+	leaf := n.find(key)
+	if leaf == nil {
+		return 0
+	}
+	bit := leaf.Key.Critbit(key)
+	r := 0
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Stop at the subtree where key diverges
+		if bit != -1 && inner.bit > bit {
+			break
+		}
+		dir := key.Direction(inner.bit)
+		if dir == 1 {
+			r += inner.child[0].len()
+		}
+		n = inner.child[dir]
+	}
+	if bit == -1 {
+		// n is the leaf equal to key
+		if inclusive {
+			r++
+		}
+	} else if key.Direction(bit) == 1 {
+		// All keys in n are less than key
+		r += n.len()
+	}
+	return r
+}
+
+// Rank returns the number of keys in the tree that are less than key.
+// If key is in the tree, Rank is its zero-based position in
+// lexicographical order.
+//
+// Time complexity: O(k) where k is the length of the key in bits, in a
+// tree keeping subtree counts (see NewCountedTree); O(n) otherwise.
+func (t *Tree[V]) Rank(key Key) int {
+	return t.root.rank(key, false)
+}
+
+// Select returns the key at the zero-based position i in
+// lexicographical order, and its value.
+// Returns false if i is out of range.
+//
+// Time complexity: O(d) where d is the depth of the tree, in a tree
+// keeping subtree counts (see NewCountedTree); O(n) otherwise.
+func (t *Tree[V]) Select(i int) (Key, V, bool) {
+	if i < 0 || i >= t.nums {
+		return leafEntry[V](nil)
+	}
+	n := t.root
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		if c := inner.child[0].len(); i >= c {
+			i -= c
+			n = inner.child[1]
+		} else {
+			n = inner.child[0]
+		}
+	}
+	return leafEntry(n.Leaf)
+}
+
+// CountRange returns the number of keys in the tree that lie between
+// lo and hi, without iterating over them.
+//
+// Time complexity: O(k) where k is the length of the longer bound
+// key in bits, in a tree keeping subtree counts (see NewCountedTree);
+// O(n) otherwise.
+func (t *Tree[V]) CountRange(lo, hi Bound) int {
+	var below, upto int
+	switch lo.kind {
+	case inclusive:
+		below = t.root.rank(lo.key, false)
+	case exclusive:
+		below = t.root.rank(lo.key, true)
+	}
+	switch hi.kind {
+	case inclusive:
+		upto = t.root.rank(hi.key, true)
+	case exclusive:
+		upto = t.root.rank(hi.key, false)
+	default:
+		upto = t.nums
+	}
+	return max(upto-below, 0)
+}
@@ -0,0 +1,297 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestMinMax(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	t.Run("empty", func(t *testing.T) {
+		if _, _, found := m.Min(); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+		if _, _, found := m.Max(); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+		if _, _, found := m.PopMin(); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+		if _, _, found := m.PopMax(); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+	})
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+	t.Run("Min", func(t *testing.T) {
+		data := dataset[0]
+		key, val, found := m.Min()
+		if !found {
+			t.Fatalf("want %v; but got %v", true, found)
+		}
+		if !key.Equal(data.Key) {
+			t.Errorf("want %v; but got %v", data.Key, key)
+		}
+		if val != data.Value {
+			t.Errorf("want %v; but got %v", data.Value, val)
+		}
+	})
+	t.Run("Max", func(t *testing.T) {
+		data := dataset[len(dataset)-1]
+		key, val, found := m.Max()
+		if !found {
+			t.Fatalf("want %v; but got %v", true, found)
+		}
+		if !key.Equal(data.Key) {
+			t.Errorf("want %v; but got %v", data.Key, key)
+		}
+		if val != data.Value {
+			t.Errorf("want %v; but got %v", data.Value, val)
+		}
+	})
+	t.Run("PopMin PopMax", func(t *testing.T) {
+		lo, hi := 0, len(dataset)-1
+		for lo <= hi {
+			var val uint32
+			var found bool
+			want := dataset[lo].Value
+			if r.IntN(2) == 0 {
+				_, val, found = m.PopMin()
+				lo++
+			} else {
+				want = dataset[hi].Value
+				_, val, found = m.PopMax()
+				hi--
+			}
+			if !found {
+				t.Fatalf("want %v; but got %v", true, found)
+			}
+			if val != want {
+				t.Errorf("want %v; but got %v", want, val)
+			}
+			if m.Len() != hi-lo+1 {
+				t.Errorf("want %v; but got %v", hi-lo+1, m.Len())
+			}
+		}
+	})
+}
+
+func TestNearest(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(Key) (Key, uint32, bool)
+		// index of the expected entry for the insertion point i of
+		// the key and whether the key was found
+		want func(i int, found bool) int
+	}{
+		{
+			name: "Ceiling",
+			fn:   m.Ceiling,
+			want: func(i int, found bool) int { return i },
+		},
+		{
+			name: "Higher",
+			fn:   m.Higher,
+			want: func(i int, found bool) int {
+				if found {
+					return i + 1
+				}
+				return i
+			},
+		},
+		{
+			name: "Floor",
+			fn:   m.Floor,
+			want: func(i int, found bool) int {
+				if found {
+					return i
+				}
+				return i - 1
+			},
+		},
+		{
+			name: "Lower",
+			fn:   m.Lower,
+			want: func(i int, found bool) int { return i - 1 },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for range 1000 {
+				key := randomBound(r, dataset).key
+				i, found := slices.BinarySearchFunc(dataset, key, func(data TestData, key Key) int {
+					return data.Key.Compare(key)
+				})
+				want := tc.want(i, found)
+				got, val, ok := tc.fn(key)
+				if want < 0 || want >= len(dataset) {
+					if ok {
+						t.Fatalf("%v(%v): want none; but got %v", tc.name, key, got)
+					}
+					continue
+				}
+				data := dataset[want]
+				if !ok {
+					t.Fatalf("%v(%v): want %v; but got none", tc.name, key, data.Key)
+				}
+				if !got.Equal(data.Key) {
+					t.Errorf("%v(%v): want %v; but got %v", tc.name, key, data.Key, got)
+				}
+				if val != data.Value {
+					t.Errorf("want %v; but got %v", data.Value, val)
+				}
+			}
+		})
+	}
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		if _, _, found := m.Ceiling(Key{}); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+		if _, _, found := m.Floor(Key{}); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+	})
+}
+
+// checkCounts verifies the leaf counts of the subtree n, if its tree
+// keeps them, and returns the number of leaves in it.
+func checkCounts[V any](t *testing.T, n Node[V]) int {
+	t.Helper()
+	inner := n.Inner
+	if inner == nil {
+		if n.Leaf == nil {
+			return 0
+		}
+		return 1
+	}
+	c := checkCounts(t, inner.child[0]) + checkCounts(t, inner.child[1])
+	if p := inner.counter(); p != nil && *p != c {
+		t.Fatalf("count: want %v; but got %v", c, *p)
+	}
+	return c
+}
+
+func TestOrderStatistics(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	m := NewCountedTree[uint32]()
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+	// Replaced values must not change the counts
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	t.Run("counts", func(t *testing.T) {
+		if m.root.Inner.counter() == nil {
+			t.Fatalf("want counts; but got none")
+		}
+		if c := checkCounts(t, m.root); c != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), c)
+		}
+	})
+	t.Run("Rank", func(t *testing.T) {
+		for i, data := range dataset {
+			if got := m.Rank(data.Key); got != i {
+				t.Errorf("Rank(%v): want %v; but got %v", data.Key, i, got)
+			}
+		}
+		for range 1000 {
+			key := randomBound(r, dataset).key
+			want, _ := slices.BinarySearchFunc(dataset, key, func(data TestData, key Key) int {
+				return data.Key.Compare(key)
+			})
+			if got := m.Rank(key); got != want {
+				t.Errorf("Rank(%v): want %v; but got %v", key, want, got)
+			}
+		}
+	})
+	t.Run("Select", func(t *testing.T) {
+		for i, data := range dataset {
+			key, val, found := m.Select(i)
+			if !found {
+				t.Fatalf("Select(%v): want %v; but got %v", i, true, found)
+			}
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+		}
+		for _, i := range []int{-1, len(dataset)} {
+			if _, _, found := m.Select(i); found {
+				t.Errorf("Select(%v): want %v; but got %v", i, false, found)
+			}
+		}
+	})
+	t.Run("CountRange", func(t *testing.T) {
+		for range 1000 {
+			lo := randomBound(r, dataset)
+			hi := randomBound(r, dataset)
+			want := 0
+			for _, data := range dataset {
+				if inBounds(data.Key, lo, hi) {
+					want++
+				}
+			}
+			if got := m.CountRange(lo, hi); got != want {
+				t.Errorf("CountRange(%v, %v): want %v; but got %v", lo, hi, want, got)
+			}
+		}
+	})
+	t.Run("uncounted", func(t *testing.T) {
+		var u Tree[uint32]
+		for _, p := range r.Perm(len(dataset)) {
+			u.Set(dataset[p].Key, dataset[p].Value)
+		}
+		if u.root.Inner.counter() != nil {
+			t.Fatalf("want no counts; but got some")
+		}
+		for i := -1; i <= len(dataset); i++ {
+			key, val, found := u.Select(i)
+			wkey, wval, wfound := m.Select(i)
+			if found != wfound || !key.Equal(wkey) || val != wval {
+				t.Errorf("Select(%v): want %v %v %v; but got %v %v %v", i, wkey, wval, wfound, key, val, found)
+			}
+		}
+		for range 1000 {
+			key := randomBound(r, dataset).key
+			if got, want := u.Rank(key), m.Rank(key); got != want {
+				t.Errorf("Rank(%v): want %v; but got %v", key, want, got)
+			}
+			if got, want := u.CountPrefix(key), m.CountPrefix(key); got != want {
+				t.Errorf("CountPrefix(%v): want %v; but got %v", key, want, got)
+			}
+			lo := randomBound(r, dataset)
+			hi := randomBound(r, dataset)
+			if got, want := u.CountRange(lo, hi), m.CountRange(lo, hi); got != want {
+				t.Errorf("CountRange(%v, %v): want %v; but got %v", lo, hi, want, got)
+			}
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		for i, p := range r.Perm(len(dataset)) {
+			m.Delete(dataset[p].Key)
+			if i%64 == 0 {
+				checkCounts(t, m.root)
+			}
+		}
+		if _, _, found := m.Select(0); found {
+			t.Errorf("want %v; but got %v", false, found)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package critbit
+
+import (
+	"iter"
+)
+
+// prefix returns the subtree containing exactly the keys that have p
+// as a prefix. Returns a zero Node if no such key exists.
+//
+// Keys sharing the prefix p have no critical bit below p.Nbits
+// between them, so they form a single subtree reached by following p
+// through the internal nodes whose critical bit lies within p.
+func (n Node[V]) prefix(p Key) Node[V] {
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Stop at the first critical bit beyond the prefix
+		if inner.bit >= p.Nbits<<1 {
+			break
+		}
+		n = inner.child[p.Direction(inner.bit)]
+	}
+	// The subtree shares its leading bits, so checking any
+	// one of its leaves is enough
+	leaf := n.find(p)
+	if leaf == nil || !leaf.Key.HasPrefix(p) {
+		return Node[V]{}
+	}
+	return n
+}
+
+// WithPrefix returns an iterator over the key-value pairs whose keys
+// have p as a prefix, in lexicographical order of keys.
+//
+// Example:
+//
+//	for key, value := range tree.WithPrefix(critbit.StringKey("app")) {
+//	    fmt.Printf("Key: %v, Value: %v\n", key, value)
+//	}
+//
+// Time complexity: O(k + m) where k is the length of p in bits and m
+// is the number of key-value pairs yielded.
+func (t *Tree[V]) WithPrefix(p Key) iter.Seq2[Key, V] {
+	return t.scanPrefix(p, false)
+}
+
+// WithPrefixBackward returns an iterator over the key-value pairs whose
+// keys have p as a prefix, in reverse lexicographical order of keys.
+func (t *Tree[V]) WithPrefixBackward(p Key) iter.Seq2[Key, V] {
+	return t.scanPrefix(p, true)
+}
+
+// scanPrefix returns an iterator over the key-value pairs under the
+// prefix p in the given traversal order.
+func (t *Tree[V]) scanPrefix(p Key, reverse bool) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		root := func() Node[V] { return t.root.prefix(p) }
+		for leaf := range t.leaves(root, Unbounded(), Unbounded(), reverse) {
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// CountPrefix returns the number of keys in the tree that have p as
+// a prefix, without iterating over them.
+//
+// Time complexity: O(k) where k is the length of p in bits, in a tree
+// keeping subtree counts (see NewCountedTree); O(k + m) otherwise,
+// where m is the number of matching keys.
+func (t *Tree[V]) CountPrefix(p Key) int {
+	return t.root.prefix(p).len()
+}
+
+// DeletePrefix removes all key-value pairs whose keys have p as
+// a prefix, and returns the number of removed pairs.
+//
+// The keys under p form a single subtree, which is detached as a whole
+// in the same way Delete removes a single leaf.
+//
+// Time complexity: O(k) where k is the length of p in bits, in a tree
+// keeping subtree counts (see NewCountedTree); O(k + m) otherwise,
+// where m is the number of removed pairs, which are counted.
+func (t *Tree[V]) DeletePrefix(p Key) int {
+	var parent *Inner[V] // parent of current node
+	var dir int          // direction taken from parent
+	n := &t.root
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Stop at the first critical bit beyond the prefix
+		if inner.bit >= p.Nbits<<1 {
+			break
+		}
+		parent = inner
+		dir = p.Direction(inner.bit)
+		n = &inner.child[dir]
+	}
+
+	leaf := n.find(p)
+	if leaf == nil || !leaf.Key.HasPrefix(p) {
+		return 0 // no key under the prefix
+	}
+	m := n.len()
+	t.detach(p, parent, dir, m)
+	return m
+}
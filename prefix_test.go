@@ -0,0 +1,143 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestWithPrefix(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	t.Run("String", func(t *testing.T) {
+		var m Tree[string]
+		for _, s := range []string{"ant", "app", "apple", "application", "apply", "b"} {
+			m.Set(StringKey(s), s)
+		}
+		var got []string
+		for _, val := range m.WithPrefix(StringKey("app")) {
+			got = append(got, val)
+		}
+		want := []string{"app", "apple", "application", "apply"}
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v; but got %v", want, got)
+		}
+	})
+	t.Run("Random", func(t *testing.T) {
+		for range 1000 {
+			p := randomBound(r, dataset).key
+			var want []uint32
+			for _, data := range dataset {
+				if data.Key.HasPrefix(p) {
+					want = append(want, data.Value)
+				}
+			}
+			var got []uint32
+			for _, val := range m.WithPrefix(p) {
+				got = append(got, val)
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("WithPrefix(%v): want %v; but got %v", p, want, got)
+			}
+			got = got[:0]
+			for _, val := range m.WithPrefixBackward(p) {
+				got = append(got, val)
+			}
+			slices.Reverse(want)
+			if !slices.Equal(got, want) {
+				t.Fatalf("WithPrefixBackward(%v): want %v; but got %v", p, want, got)
+			}
+		}
+	})
+	t.Run("WithPrefix break", func(t *testing.T) {
+		i := 0
+		for range m.WithPrefix(Key{}) {
+			if i >= len(dataset)/2 {
+				break
+			}
+			i++
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		for key := range m.WithPrefix(Key{}) {
+			t.Errorf("unexpected key %v", key)
+		}
+	})
+}
+
+func TestCountPrefix(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	if got := m.CountPrefix(Key{}); got != 0 {
+		t.Errorf("want %v; but got %v", 0, got)
+	}
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+	if got := m.CountPrefix(Key{}); got != len(dataset) {
+		t.Errorf("want %v; but got %v", len(dataset), got)
+	}
+	for range 1000 {
+		p := randomBound(r, dataset).key
+		want := 0
+		for _, data := range dataset {
+			if data.Key.HasPrefix(p) {
+				want++
+			}
+		}
+		if got := m.CountPrefix(p); got != want {
+			t.Errorf("CountPrefix(%v): want %v; but got %v", p, want, got)
+		}
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	m := NewCountedTree[uint32]()
+	if got := m.DeletePrefix(Key{}); got != 0 {
+		t.Errorf("want %v; but got %v", 0, got)
+	}
+	for range 100 {
+		for _, p := range r.Perm(len(dataset)) {
+			m.Set(dataset[p].Key, dataset[p].Value)
+		}
+		p := randomBound(r, dataset).key
+		var want []uint32
+		n := 0
+		for _, data := range dataset {
+			if data.Key.HasPrefix(p) {
+				n++
+			} else {
+				want = append(want, data.Value)
+			}
+		}
+		if got := m.DeletePrefix(p); got != n {
+			t.Errorf("DeletePrefix(%v): want %v; but got %v", p, n, got)
+		}
+		if m.Len() != len(want) {
+			t.Errorf("want %v; but got %v", len(want), m.Len())
+		}
+		checkCounts(t, m.root)
+		got := slices.Collect(m.Values())
+		if !slices.Equal(got, want) {
+			t.Fatalf("DeletePrefix(%v): want %v; but got %v", p, want, got)
+		}
+	}
+	t.Run("all", func(t *testing.T) {
+		n := m.Len()
+		if got := m.DeletePrefix(Key{}); got != n {
+			t.Errorf("want %v; but got %v", n, got)
+		}
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+	})
+}
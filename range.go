@@ -0,0 +1,220 @@
+package critbit
+
+import (
+	"iter"
+)
+
+// boundKind specifies how a Bound limits a key range.
+type boundKind int
+
+const (
+	unbounded boundKind = iota // no limit
+	inclusive                  // the bound key is part of the range
+	exclusive                  // the bound key is not part of the range
+)
+
+// Bound represents one end of a key range.
+// The zero value of Bound is unbounded.
+type Bound struct {
+	key  Key
+	kind boundKind
+}
+
+// Inclusive returns a Bound that includes key in the range.
+func Inclusive(key Key) Bound {
+	return Bound{key: key, kind: inclusive}
+}
+
+// Exclusive returns a Bound that excludes key from the range.
+func Exclusive(key Key) Bound {
+	return Bound{key: key, kind: exclusive}
+}
+
+// Unbounded returns a Bound that does not limit the range.
+// It is equivalent to the zero value of Bound.
+func Unbounded() Bound {
+	return Bound{}
+}
+
+// contains reports whether key lies within b, where b is used as
+// the end bound of a scan in the given direction
+// (0 for forward, 1 for reverse).
+func (b Bound) contains(key Key, dir int) bool {
+	if b.kind == unbounded {
+		return true
+	}
+	c := key.Compare(b.key)
+	if dir == 1 {
+		c = -c
+	}
+	if b.kind == inclusive {
+		return c <= 0
+	}
+	return c < 0
+}
+
+// Range returns an iterator over the key-value pairs whose keys lie
+// between lo and hi, in lexicographical order of keys.
+// The iterator descends directly to lo instead of scanning the tree
+// from the beginning, and stops as soon as a key passes hi.
+//
+// Example:
+//
+//	lo := critbit.Inclusive(critbit.StringKey("b"))
+//	hi := critbit.Exclusive(critbit.StringKey("d"))
+//	for key, value := range tree.Range(lo, hi) {
+//	    fmt.Printf("Key: %v, Value: %v\n", key, value)
+//	}
+//
+// Time complexity: O(k + m) where k is the length of lo in bits and m
+// is the number of key-value pairs yielded.
+func (t *Tree[V]) Range(lo, hi Bound) iter.Seq2[Key, V] {
+	return t.scanRange(lo, hi, false)
+}
+
+// RangeBackward returns an iterator over the key-value pairs whose keys
+// lie between lo and hi, in reverse lexicographical order of keys.
+// The iterator descends directly to hi and stops as soon as a key
+// passes lo.
+func (t *Tree[V]) RangeBackward(lo, hi Bound) iter.Seq2[Key, V] {
+	return t.scanRange(hi, lo, true)
+}
+
+// scanRange returns an iterator over the key-value pairs between start
+// and end in the given traversal order.
+func (t *Tree[V]) scanRange(start, end Bound, reverse bool) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for leaf := range t.leaves(t.rootNode, start, end, reverse) {
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// rangeCut describes how a bound of a range cuts through the tree.
+type rangeCut struct {
+	key       Key
+	bit       int  // critical bit between key and the tree, -1 if key is in the tree
+	inclusive bool // the key is part of the range
+	dir       int  // 0 for the lower bound, 1 for the upper bound
+}
+
+// cut returns how the bound b cuts through the tree, where dir is 0 if
+// b is the lower bound and 1 if b is the upper bound.
+// Returns nil if b is unbounded or the tree is empty.
+func (t *Tree[V]) cut(b Bound, dir int) *rangeCut {
+	if b.kind == unbounded {
+		return nil
+	}
+	leaf := t.root.find(b.key)
+	if leaf == nil {
+		return nil
+	}
+	return &rangeCut{
+		key:       b.key,
+		bit:       leaf.Key.Critbit(b.key),
+		inclusive: b.kind == inclusive,
+		dir:       dir,
+	}
+}
+
+// through reports whether the cut passes through an internal node with
+// the given critical bit, rather than ending above it.
+func (c *rangeCut) through(bit int) bool {
+	return c.bit == -1 || bit < c.bit
+}
+
+// inside reports whether the subtree where the cut ends lies within
+// the bound.
+func (c *rangeCut) inside() bool {
+	if c.bit == -1 {
+		// The cut ends at the leaf equal to the bound key
+		return c.inclusive
+	}
+	return c.key.Direction(c.bit) == c.dir
+}
+
+// pruneRange removes the leaves of the subtree at n that lie between
+// the cuts lo and hi, and returns the number of removed leaves.
+// A nil cut does not limit the subtree.
+//
+// Only the nodes on the paths of the two cuts are visited; any other
+// subtree lies either entirely outside the range and is kept, or
+// entirely inside and is removed as a whole.
+func (t *Tree[V]) pruneRange(n *Node[V], lo, hi *rangeCut) int {
+	inner := n.Inner
+	// Resolve the cuts that end at this subtree
+	if lo != nil && (inner == nil || !lo.through(inner.bit)) {
+		if !lo.inside() {
+			return 0
+		}
+		lo = nil
+	}
+	if hi != nil && (inner == nil || !hi.through(inner.bit)) {
+		if !hi.inside() {
+			return 0
+		}
+		hi = nil
+	}
+	if lo == nil && hi == nil {
+		// The whole subtree is in the range
+		m := n.len()
+		*n = Node[V]{}
+		return m
+	}
+
+	// At least one cut passes through this internal node,
+	// which must be modifiable to remove leaves below it
+	inner = t.own(n).Inner
+	m := 0
+	for dir := range 2 {
+		clo, chi := lo, hi
+		if lo != nil && lo.key.Direction(inner.bit) != dir {
+			if dir == lo.dir {
+				continue // below the lower bound
+			}
+			clo = nil
+		}
+		if hi != nil && hi.key.Direction(inner.bit) != dir {
+			if dir == hi.dir {
+				continue // above the upper bound
+			}
+			chi = nil
+		}
+		m += t.pruneRange(&inner.child[dir], clo, chi)
+	}
+	if m == 0 {
+		return 0
+	}
+
+	if c := inner.counter(); c != nil {
+		*c -= m
+	}
+	// Replace the node with the remaining child
+	if inner.child[0].empty() {
+		*n = inner.child[1]
+	} else if inner.child[1].empty() {
+		*n = inner.child[0]
+	}
+	return m
+}
+
+// DeleteRange removes all key-value pairs whose keys lie between lo
+// and hi, and returns the number of removed pairs.
+//
+// Subtrees lying entirely inside the range are removed as a whole
+// instead of leaf by leaf.
+//
+// Time complexity: O(k) where k is the length of the longer bound
+// key in bits, in a tree keeping subtree counts (see NewCountedTree);
+// O(k + m) otherwise, where m is the number of removed pairs, which
+// are counted.
+func (t *Tree[V]) DeleteRange(lo, hi Bound) int {
+	m := t.pruneRange(&t.root, t.cut(lo, 0), t.cut(hi, 1))
+	if m > 0 {
+		t.nums -= m
+		t.mods++
+	}
+	return m
+}
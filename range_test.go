@@ -0,0 +1,185 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// setupBitsDataset returns n distinct keys of random bit lengths
+// (up to 16 bits) sorted in key order. Each value is the index of
+// its key in the returned slice.
+func setupBitsDataset(r *rand.Rand, n int) []TestData {
+	seen := make(map[string]bool)
+	dataset := make([]TestData, 0, n)
+	for len(dataset) < n {
+		nbits := r.IntN(17)
+		v := uint16(r.Uint32()) &^ (0xffff >> nbits)
+		key := BitsKey([]byte{byte(v >> 8), byte(v)}, nbits)
+		if s := bitString(key); !seen[s] {
+			seen[s] = true
+			dataset = append(dataset, TestData{Key: key})
+		}
+	}
+	slices.SortFunc(dataset, func(a, b TestData) int {
+		return a.Key.Compare(b.Key)
+	})
+	for i := range dataset {
+		dataset[i].Value = uint32(i)
+	}
+	return dataset
+}
+
+// inBounds reports whether key lies between lo and hi.
+func inBounds(key Key, lo, hi Bound) bool {
+	switch lo.kind {
+	case inclusive:
+		if key.Compare(lo.key) < 0 {
+			return false
+		}
+	case exclusive:
+		if key.Compare(lo.key) <= 0 {
+			return false
+		}
+	}
+	switch hi.kind {
+	case inclusive:
+		if key.Compare(hi.key) > 0 {
+			return false
+		}
+	case exclusive:
+		if key.Compare(hi.key) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// randomBound returns a random bound around the keys of dataset.
+func randomBound(r *rand.Rand, dataset []TestData) Bound {
+	var key Key
+	if r.IntN(2) == 0 {
+		key = dataset[r.IntN(len(dataset))].Key
+	} else {
+		nbits := r.IntN(17)
+		v := uint16(r.Uint32()) &^ (0xffff >> nbits)
+		key = BitsKey([]byte{byte(v >> 8), byte(v)}, nbits)
+	}
+	switch r.IntN(3) {
+	case 0:
+		return Inclusive(key)
+	case 1:
+		return Exclusive(key)
+	default:
+		return Unbounded()
+	}
+}
+
+func TestRange(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	t.Run("Uint32", func(t *testing.T) {
+		var m Tree[uint32]
+		dataset := setupDataset(256)
+		for _, data := range dataset {
+			m.Set(data.Key, data.Value)
+		}
+		i := 16
+		for key, val := range m.Range(Inclusive(Uint32Key(16)), Exclusive(Uint32Key(32))) {
+			data := dataset[i]
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+			i++
+		}
+		if i != 32 {
+			t.Errorf("want %v; but got %v", 32, i)
+		}
+	})
+	t.Run("Random", func(t *testing.T) {
+		for range 1000 {
+			lo := randomBound(r, dataset)
+			hi := randomBound(r, dataset)
+			var want []uint32
+			for _, data := range dataset {
+				if inBounds(data.Key, lo, hi) {
+					want = append(want, data.Value)
+				}
+			}
+			var got []uint32
+			for _, val := range m.Range(lo, hi) {
+				got = append(got, val)
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("Range(%v, %v): want %v; but got %v", lo, hi, want, got)
+			}
+			got = got[:0]
+			for _, val := range m.RangeBackward(lo, hi) {
+				got = append(got, val)
+			}
+			slices.Reverse(want)
+			if !slices.Equal(got, want) {
+				t.Fatalf("RangeBackward(%v, %v): want %v; but got %v", lo, hi, want, got)
+			}
+		}
+	})
+	t.Run("Range break", func(t *testing.T) {
+		i := 0
+		for range m.Range(Unbounded(), Unbounded()) {
+			if i >= len(dataset)/2 {
+				break
+			}
+			i++
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		var m Tree[uint32]
+		for key := range m.Range(Inclusive(Uint32Key(0)), Unbounded()) {
+			t.Errorf("unexpected key %v", key)
+		}
+	})
+}
+
+func TestDeleteRange(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	m := NewCountedTree[uint32]()
+	if got := m.DeleteRange(Unbounded(), Unbounded()); got != 0 {
+		t.Errorf("want %v; but got %v", 0, got)
+	}
+	for range 1000 {
+		for _, p := range r.Perm(len(dataset)) {
+			m.Set(dataset[p].Key, dataset[p].Value)
+		}
+		lo := randomBound(r, dataset)
+		hi := randomBound(r, dataset)
+		var want []uint32
+		n := 0
+		for _, data := range dataset {
+			if inBounds(data.Key, lo, hi) {
+				n++
+			} else {
+				want = append(want, data.Value)
+			}
+		}
+		if got := m.DeleteRange(lo, hi); got != n {
+			t.Errorf("DeleteRange(%v, %v): want %v; but got %v", lo, hi, n, got)
+		}
+		if m.Len() != len(want) {
+			t.Errorf("want %v; but got %v", len(want), m.Len())
+		}
+		checkCounts(t, m.root)
+		got := slices.Collect(m.Values())
+		if !slices.Equal(got, want) {
+			t.Fatalf("DeleteRange(%v, %v): want %v; but got %v", lo, hi, want, got)
+		}
+	}
+}
@@ -6,11 +6,21 @@ package critbit
 //
 // Scanner can traverse the tree in either forward (lexicographical)
 // or reverse order depending on the reverse parameter passed to NewScanner.
+//
+// A Scanner walks the nodes that existed when it was positioned, so
+// modifying the tree while scanning leaves it traversing stale nodes.
+// To resume in the modified tree, create a new Scanner and call
+// SeekAfter with the last key returned. The iterators of Tree do this
+// automatically.
 type Scanner[V any] struct {
 	// direction for traversal: 0 for forward, 1 for reverse
 	dir int
 	// stack of nodes for iterative traversal
 	stack []Node[V]
+	// root node of the traversal, used by Seek
+	root Node[V]
+	// bound where the traversal stops
+	end Bound
 }
 
 // NewScanner creates a new Scanner for iterating over a crit-bit tree.
@@ -24,7 +34,7 @@ type Scanner[V any] struct {
 // Example:
 //
 //	// Forward traversal
-//	scanner := NewScanner(tree.root, false)
+//	scanner := tree.Scanner(false)
 //	for {
 //	    leaf := scanner.Scan()
 //	    if leaf == nil {
@@ -37,10 +47,48 @@ func NewScanner[V any](root Node[V], reverse bool) *Scanner[V] {
 	if reverse {
 		s.dir = 1
 	}
+	s.root = root
 	s.push(root)
 	return s
 }
 
+// Seek repositions the scanner so that the next call to Scan returns
+// the first leaf whose key is greater than or equal to key,
+// or less than or equal to key for a reverse scanner.
+//
+// Seek lets paginated traversals resume from a cursor key without
+// rescanning the tree from the beginning.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (s *Scanner[V]) Seek(key Key) {
+	s.seek(key, true)
+}
+
+// SeekAfter is like Seek but skips a leaf equal to key, so that
+// the next call to Scan returns the first leaf strictly after key
+// in the traversal order.
+func (s *Scanner[V]) SeekAfter(key Key) {
+	s.seek(key, false)
+}
+
+// SetEnd sets the bound where the traversal stops.
+// Once Scan reaches a leaf outside end, it returns nil.
+// The end bound is the upper bound of a forward scanner and the lower
+// bound of a reverse scanner. An unbounded end, the default, scans to
+// the last leaf of the tree.
+//
+// Example:
+//
+//	scanner := tree.Scanner(false)
+//	scanner.SeekAfter(cursor)
+//	scanner.SetEnd(critbit.Exclusive(limit))
+//	for leaf := scanner.Scan(); leaf != nil; leaf = scanner.Scan() {
+//	    fmt.Printf("Key: %v, Value: %v\n", leaf.Key, leaf.Value)
+//	}
+func (s *Scanner[V]) SetEnd(end Bound) {
+	s.end = end
+}
+
 // Scan returns the next leaf node in the traversal order.
 // Returns nil when there are no more nodes to visit.
 //
@@ -64,7 +112,13 @@ func (s *Scanner[V]) Scan() *Leaf[V] {
 		// Continue with the node we want to visit first
 		n = inner.child[s.dir]
 	}
-	return n.Leaf
+	leaf := n.Leaf
+	if leaf != nil && !s.end.contains(leaf.Key, s.dir) {
+		// Passed the end bound
+		s.stack = s.stack[:0]
+		return nil
+	}
+	return leaf
 }
 
 // push adds a node to the traversal stack.
@@ -86,3 +140,59 @@ func (s *Scanner[V]) pop() Node[V] {
 	s.stack = s.stack[:n]
 	return node
 }
+
+// seek discards the pending nodes and positions the scanner so that the
+// next call to Scan returns the first leaf at or after key in the
+// traversal order. If inclusive is false, a leaf equal to key is skipped.
+//
+// The walk follows key from the root down to the node where key diverges
+// from the tree, pushing every subtree that lies after key in the
+// traversal order on the way.
+// Internal method used by the scanning algorithm.
+func (s *Scanner[V]) seek(key Key, inclusive bool) {
+	s.stack = s.stack[:0]
+	leaf := s.root.find(key)
+	if leaf == nil {
+		return
+	}
+	bit := leaf.Key.Critbit(key)
+	n := s.root
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		// Stop at the subtree where key diverges
+		if bit != -1 && inner.bit > bit {
+			break
+		}
+		dir := key.Direction(inner.bit)
+		if dir == s.dir {
+			// The other child comes after key
+			s.push(inner.child[dir^1])
+		}
+		n = inner.child[dir]
+	}
+	if bit == -1 {
+		// n is the leaf equal to key
+		if inclusive {
+			s.push(n)
+		}
+		return
+	}
+	// All keys in n are on the same side of key
+	if key.Direction(bit) == s.dir {
+		s.push(n)
+	}
+}
+
+// seekBound positions the scanner at the first leaf within b,
+// where b is used as the start bound of the traversal.
+// An unbounded b leaves the scanner unchanged.
+// Internal method used by the scanning algorithm.
+func (s *Scanner[V]) seekBound(b Bound) {
+	if b.kind == unbounded {
+		return
+	}
+	s.seek(b.key, b.kind == inclusive)
+}
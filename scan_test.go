@@ -0,0 +1,133 @@
+package critbit
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestScannerSeek(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var m Tree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+
+	t.Run("Seek", func(t *testing.T) {
+		for _, reverse := range []bool{false, true} {
+			s := m.Scanner(reverse)
+			for range 1000 {
+				key := randomBound(r, dataset).key
+				want, _, found := m.Ceiling(key)
+				if reverse {
+					want, _, found = m.Floor(key)
+				}
+				s.Seek(key)
+				leaf := s.Scan()
+				if !found {
+					if leaf != nil {
+						t.Fatalf("Seek(%v): want none; but got %v", key, leaf.Key)
+					}
+					continue
+				}
+				if leaf == nil {
+					t.Fatalf("Seek(%v): want %v; but got none", key, want)
+				}
+				if !leaf.Key.Equal(want) {
+					t.Errorf("Seek(%v): want %v; but got %v", key, want, leaf.Key)
+				}
+			}
+		}
+	})
+	t.Run("SeekAfter", func(t *testing.T) {
+		for _, reverse := range []bool{false, true} {
+			s := m.Scanner(reverse)
+			for range 1000 {
+				key := randomBound(r, dataset).key
+				want, _, found := m.Higher(key)
+				if reverse {
+					want, _, found = m.Lower(key)
+				}
+				s.SeekAfter(key)
+				leaf := s.Scan()
+				if !found {
+					if leaf != nil {
+						t.Fatalf("SeekAfter(%v): want none; but got %v", key, leaf.Key)
+					}
+					continue
+				}
+				if leaf == nil {
+					t.Fatalf("SeekAfter(%v): want %v; but got none", key, want)
+				}
+				if !leaf.Key.Equal(want) {
+					t.Errorf("SeekAfter(%v): want %v; but got %v", key, want, leaf.Key)
+				}
+			}
+		}
+	})
+	t.Run("Pagination", func(t *testing.T) {
+		const size = 10
+		i := 0
+		var cursor *Key
+		for {
+			s := m.Scanner(false)
+			if cursor != nil {
+				s.SeekAfter(*cursor)
+			}
+			n := 0
+			for leaf := s.Scan(); leaf != nil && n < size; leaf = s.Scan() {
+				data := dataset[i]
+				if !leaf.Key.Equal(data.Key) {
+					t.Fatalf("want %v; but got %v", data.Key, leaf.Key)
+				}
+				cursor = &leaf.Key
+				i++
+				n++
+			}
+			if n == 0 {
+				break
+			}
+		}
+		if i != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), i)
+		}
+	})
+	t.Run("SetEnd", func(t *testing.T) {
+		lo, hi := 100, 200
+		s := m.Scanner(false)
+		s.Seek(dataset[lo].Key)
+		s.SetEnd(Exclusive(dataset[hi].Key))
+		i := lo
+		for leaf := s.Scan(); leaf != nil; leaf = s.Scan() {
+			data := dataset[i]
+			if !leaf.Key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, leaf.Key)
+			}
+			i++
+		}
+		if i != hi {
+			t.Errorf("want %v; but got %v", hi, i)
+		}
+		// Stays exhausted
+		if leaf := s.Scan(); leaf != nil {
+			t.Errorf("want nil; but got %v", leaf.Key)
+		}
+	})
+	t.Run("SetEnd reverse", func(t *testing.T) {
+		lo, hi := 100, 200
+		s := m.Scanner(true)
+		s.Seek(dataset[hi].Key)
+		s.SetEnd(Inclusive(dataset[lo].Key))
+		i := hi
+		for leaf := s.Scan(); leaf != nil; leaf = s.Scan() {
+			data := dataset[i]
+			if !leaf.Key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, leaf.Key)
+			}
+			i--
+		}
+		if i != lo-1 {
+			t.Errorf("want %v; but got %v", lo-1, i)
+		}
+	})
+}
@@ -0,0 +1,334 @@
+package critbit
+
+import (
+	"iter"
+	"sync"
+)
+
+// ShardedTree is a crit-bit tree partitioned into independently locked
+// shards, for write-heavy workloads from many goroutines.
+// It is safe for concurrent use.
+//
+// Keys are assigned to shards by their first bits, with missing bits of
+// shorter keys taken as zero. Since the shard index preserves key
+// order, every key in a shard sorts before every key in the next shard,
+// and ordered iteration simply visits the shards in turn.
+//
+// Operations on a single key lock only its shard. Operations spanning
+// several shards, such as Len and the iterators, are not atomic with
+// respect to writers: each shard is observed consistently, through
+// a copy-on-write snapshot for iterators, but different shards may be
+// observed at different times.
+//
+// The zero value of ShardedTree is an empty tree with a single shard,
+// ready for use. Use NewShardedTree to partition the keyspace.
+type ShardedTree[V any] struct {
+	bits   int // number of leading key bits selecting the shard
+	shards []shard[V]
+	single shard[V] // the only shard of the zero value
+}
+
+// shard is a Tree with its own lock.
+type shard[V any] struct {
+	mu   sync.RWMutex
+	tree Tree[V]
+	snap *Tree[V] // last snapshot, never modified
+	seen int      // modifications of the tree when snap was taken
+}
+
+// NewShardedTree returns an empty ShardedTree that partitions the
+// keyspace by the first bits of the key into 1<<bits shards.
+// bits must be between 0 and 16.
+func NewShardedTree[V any](bits int) *ShardedTree[V] {
+	if bits < 0 || bits > 16 {
+		panic("critbit: shard bits out of range")
+	}
+	return &ShardedTree[V]{
+		bits:   bits,
+		shards: make([]shard[V], 1<<bits),
+	}
+}
+
+// shardIndex returns the index, among 1<<bits shards partitioning the
+// keyspace by the leading bits, of the shard holding keys whose first
+// n bits are those of key, followed by zeros.
+func shardIndex(key Key, n, bits int) int {
+	i := 0
+	for b := range bits {
+		i <<= 1
+		if b < n && key.Data[b>>3]&(0x80>>(b&7)) != 0 {
+			i |= 1
+		}
+	}
+	return i
+}
+
+// shardOf returns the index of the shard holding key.
+func shardOf(key Key, bits int) int {
+	return shardIndex(key, min(bits, key.Nbits), bits)
+}
+
+// shardsInRange returns the indices of the first and last shards that
+// can hold keys between lo and hi.
+func shardsInRange(lo, hi Bound, bits int) (first, last int) {
+	first, last = 0, 1<<bits-1
+	if lo.kind != unbounded {
+		first = shardOf(lo.key, bits)
+	}
+	if hi.kind != unbounded {
+		last = shardOf(hi.key, bits)
+	}
+	return first, last
+}
+
+// shardsWithPrefix returns the indices of the first and last shards
+// that can hold keys with p as a prefix.
+func shardsWithPrefix(p Key, bits int) (first, last int) {
+	n := min(bits, p.Nbits)
+	first = shardIndex(p, n, bits)
+	return first, first + 1<<(bits-n) - 1
+}
+
+// prefixShards appends to idx the indices of the distinct shards that
+// can hold prefixes of key, from the shard of the longest prefixes
+// down, and returns the extended slice.
+//
+// A prefix shorter than the shard bits lives in the shard given by its
+// own bits, which may differ from the shard of key. Each group of
+// prefix lengths sharing a shard is contiguous, so the shards come in
+// decreasing order of both index and prefix length.
+func prefixShards(idx []int, key Key, bits int) []int {
+	for n := min(bits, key.Nbits); n >= 0; n-- {
+		i := shardIndex(key, n, bits)
+		if len(idx) == 0 || idx[len(idx)-1] != i {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// at returns the shard at index i.
+func (t *ShardedTree[V]) at(i int) *shard[V] {
+	if t.shards == nil {
+		return &t.single
+	}
+	return &t.shards[i]
+}
+
+// shard returns the shard holding key.
+func (t *ShardedTree[V]) shard(key Key) *shard[V] {
+	return t.at(shardOf(key, t.bits))
+}
+
+// Len returns the number of key-value pairs in the tree.
+func (t *ShardedTree[V]) Len() int {
+	n := 0
+	for i := range 1 << t.bits {
+		s := t.at(i)
+		s.mu.RLock()
+		n += s.tree.Len()
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Get retrieves the value associated with the given key.
+// See Tree.Get.
+func (t *ShardedTree[V]) Get(key Key) (V, bool) {
+	s := t.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Get(key)
+}
+
+// Set inserts a key-value pair into the tree or updates the value
+// if the key already exists.
+func (t *ShardedTree[V]) Set(key Key, value V) {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Set(key, value)
+}
+
+// Update performs an atomic read-modify-write of the value for the key.
+// See Tree.Update. fn is called with the shard locked and must not
+// access the tree.
+func (t *ShardedTree[V]) Update(key Key, fn func(old V, exists bool) (V, bool)) {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Update(key, fn)
+}
+
+// Delete removes the key-value pair with the given key from the tree.
+// If the key does not exist, Delete is a no-op.
+func (t *ShardedTree[V]) Delete(key Key) {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Delete(key)
+}
+
+// LoadAndDelete removes the key-value pair with the given key from the
+// tree and returns the removed value. See Tree.LoadAndDelete.
+func (t *ShardedTree[V]) LoadAndDelete(key Key) (V, bool) {
+	s := t.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.LoadAndDelete(key)
+}
+
+// Longest performs longest prefix matching on the entire tree.
+// See Tree.Longest.
+func (t *ShardedTree[V]) Longest(key Key) (V, bool) {
+	_, val, found := t.LongestPrefix(key)
+	return val, found
+}
+
+// LongestPrefix is like Longest but also returns the matched key.
+//
+// The shards that can hold prefixes of key are searched from the
+// longest prefix length down, so the first match found is the longest.
+func (t *ShardedTree[V]) LongestPrefix(key Key) (Key, V, bool) {
+	var buf [17]int
+	for _, i := range prefixShards(buf[:0], key, t.bits) {
+		s := t.at(i)
+		s.mu.RLock()
+		k, v, found := leafEntry(s.tree.root.longest(key))
+		s.mu.RUnlock()
+		if found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// snapshot returns a copy of the shard to iterate over without holding
+// the lock, or nil if the shard is empty. The copy must not be
+// modified, as it is shared by all iterations until the shard changes.
+//
+// Like SyncTree, the shard reuses its last snapshot while the tree is
+// unchanged, so that iterations do not keep disowning the nodes of the
+// tree and making the next writes copy their paths.
+func (s *shard[V]) snapshot() *Tree[V] {
+	s.mu.RLock()
+	snap, ok := s.cached()
+	s.mu.RUnlock()
+	if ok {
+		return snap
+	}
+	// Clone modifies the tree, so it needs the exclusive lock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+// cached returns the snapshot of the shard and true if no new one is
+// needed: the last snapshot if the tree is unchanged since, or nil if
+// the tree is empty. The caller must hold the lock.
+func (s *shard[V]) cached() (*Tree[V], bool) {
+	if s.tree.Len() == 0 {
+		return nil, true
+	}
+	if s.snap != nil && s.seen == s.tree.mods {
+		return s.snap, true
+	}
+	return nil, false
+}
+
+// refresh returns the snapshot of the shard, taking a new one if
+// needed. The caller must hold the exclusive lock.
+func (s *shard[V]) refresh() *Tree[V] {
+	if snap, ok := s.cached(); ok {
+		return snap
+	}
+	s.snap = s.tree.Clone()
+	s.seen = s.tree.mods
+	return s.snap
+}
+
+// scan returns an iterator over the shards from index lo to hi,
+// inclusive, in the given traversal order. seq selects what to iterate
+// in each shard snapshot.
+func (t *ShardedTree[V]) scan(lo, hi int, reverse bool, seq func(*Tree[V]) iter.Seq2[Key, V]) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for j := range hi - lo + 1 {
+			i := lo + j
+			if reverse {
+				i = hi - j
+			}
+			snap := t.at(i).snapshot()
+			if snap == nil {
+				continue
+			}
+			for key, val := range seq(snap) {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// All returns an iterator over all key-value pairs in the tree
+// in lexicographical order of keys.
+// Each shard is iterated over a snapshot taken when the iteration
+// reaches it, so the loop body may modify the tree. Empty shards are
+// skipped, and a shard unchanged since its last snapshot reuses it.
+func (t *ShardedTree[V]) All() iter.Seq2[Key, V] {
+	return t.scan(0, 1<<t.bits-1, false, (*Tree[V]).All)
+}
+
+// Backward returns an iterator over all key-value pairs in the tree
+// in reverse lexicographical order of keys.
+// Shards are iterated over snapshots like All.
+func (t *ShardedTree[V]) Backward() iter.Seq2[Key, V] {
+	return t.scan(0, 1<<t.bits-1, true, (*Tree[V]).Backward)
+}
+
+// Keys returns an iterator over all keys in the tree
+// in lexicographical order.
+// Shards are iterated over snapshots like All.
+func (t *ShardedTree[V]) Keys() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for key := range t.All() {
+			if !yield(key) {
+				break
+			}
+		}
+	}
+}
+
+// Values returns an iterator over all values in the tree in the order
+// corresponding to their keys' lexicographical order.
+// Shards are iterated over snapshots like All.
+func (t *ShardedTree[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range t.All() {
+			if !yield(val) {
+				break
+			}
+		}
+	}
+}
+
+// Range returns an iterator over the key-value pairs whose keys lie
+// between lo and hi, in lexicographical order of keys.
+// Shards are iterated over snapshots like All.
+func (t *ShardedTree[V]) Range(lo, hi Bound) iter.Seq2[Key, V] {
+	first, last := shardsInRange(lo, hi, t.bits)
+	return t.scan(first, last, false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.Range(lo, hi)
+	})
+}
+
+// WithPrefix returns an iterator over the key-value pairs whose keys
+// have p as a prefix, in lexicographical order of keys.
+// Only the shards that can hold such keys are visited, over snapshots
+// like All.
+func (t *ShardedTree[V]) WithPrefix(p Key) iter.Seq2[Key, V] {
+	first, last := shardsWithPrefix(p, t.bits)
+	return t.scan(first, last, false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.WithPrefix(p)
+	})
+}
@@ -0,0 +1,272 @@
+package critbit
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestShardedTree(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var want []uint32
+	for _, data := range dataset {
+		want = append(want, data.Value)
+	}
+	var ref Tree[uint32]
+	for _, data := range dataset {
+		ref.Set(data.Key, data.Value)
+	}
+
+	for _, bits := range []int{0, 1, 4, 12} {
+		m := NewShardedTree[uint32](bits)
+		for _, p := range r.Perm(len(dataset)) {
+			m.Set(dataset[p].Key, dataset[p].Value)
+		}
+
+		t.Run(fmt.Sprintf("bits=%v/Get", bits), func(t *testing.T) {
+			if m.Len() != len(dataset) {
+				t.Errorf("want %v; but got %v", len(dataset), m.Len())
+			}
+			for _, data := range dataset {
+				val, found := m.Get(data.Key)
+				if !found {
+					t.Fatalf("%v not found", data.Key)
+				}
+				if val != data.Value {
+					t.Errorf("want %v; but got %v", data.Value, val)
+				}
+			}
+		})
+		t.Run(fmt.Sprintf("bits=%v/All", bits), func(t *testing.T) {
+			if got := slices.Collect(m.Values()); !slices.Equal(got, want) {
+				t.Fatalf("want %v; but got %v", want, got)
+			}
+			i := 0
+			for key := range m.Keys() {
+				if !key.Equal(dataset[i].Key) {
+					t.Errorf("want %v; but got %v", dataset[i].Key, key)
+				}
+				i++
+			}
+			var got []uint32
+			for _, val := range m.Backward() {
+				got = append(got, val)
+			}
+			backward := slices.Clone(want)
+			slices.Reverse(backward)
+			if !slices.Equal(got, backward) {
+				t.Errorf("want %v; but got %v", backward, got)
+			}
+		})
+		t.Run(fmt.Sprintf("bits=%v/Range", bits), func(t *testing.T) {
+			for range 200 {
+				lo := randomBound(r, dataset)
+				hi := randomBound(r, dataset)
+				var want []uint32
+				for _, val := range ref.Range(lo, hi) {
+					want = append(want, val)
+				}
+				var got []uint32
+				for _, val := range m.Range(lo, hi) {
+					got = append(got, val)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("Range(%v, %v): want %v; but got %v", lo, hi, want, got)
+				}
+			}
+		})
+		t.Run(fmt.Sprintf("bits=%v/WithPrefix", bits), func(t *testing.T) {
+			for range 200 {
+				p := randomBound(r, dataset).key
+				var want []uint32
+				for _, val := range ref.WithPrefix(p) {
+					want = append(want, val)
+				}
+				var got []uint32
+				for _, val := range m.WithPrefix(p) {
+					got = append(got, val)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("WithPrefix(%v): want %v; but got %v", p, want, got)
+				}
+			}
+		})
+		t.Run(fmt.Sprintf("bits=%v/Longest", bits), func(t *testing.T) {
+			for range 1000 {
+				key := randomBound(r, dataset).key
+				wantKey, wantVal, wantFound := ref.LongestPrefix(key)
+				gotKey, gotVal, gotFound := m.LongestPrefix(key)
+				if gotFound != wantFound {
+					t.Fatalf("LongestPrefix(%v): want %v; but got %v", key, wantFound, gotFound)
+				}
+				if !gotKey.Equal(wantKey) {
+					t.Errorf("LongestPrefix(%v): want %v; but got %v", key, wantKey, gotKey)
+				}
+				if gotVal != wantVal {
+					t.Errorf("want %v; but got %v", wantVal, gotVal)
+				}
+			}
+		})
+		t.Run(fmt.Sprintf("bits=%v/Delete", bits), func(t *testing.T) {
+			for _, p := range r.Perm(len(dataset)) {
+				data := dataset[p]
+				val, found := m.LoadAndDelete(data.Key)
+				if !found || val != data.Value {
+					t.Fatalf("want %v; but got %v", data.Value, val)
+				}
+			}
+			if m.Len() != 0 {
+				t.Errorf("want %v; but got %v", 0, m.Len())
+			}
+			if _, found := m.Longest(Key{}); found {
+				t.Errorf("want %v; but got %v", false, found)
+			}
+		})
+	}
+	t.Run("snapshot reuse", func(t *testing.T) {
+		m := NewShardedTree[uint32](16)
+		allocs := testing.AllocsPerRun(10, func() {
+			for range m.All() {
+			}
+		})
+		if allocs > 4 {
+			t.Errorf("All on empty shards: want at most %v allocations; but got %v", 4, allocs)
+		}
+		data := dataset[len(dataset)-1]
+		m.Set(data.Key, data.Value)
+		s := m.shard(data.Key)
+		for range m.All() {
+		}
+		snap := s.snap
+		for range m.All() {
+		}
+		if s.snap != snap {
+			t.Errorf("snapshot taken again for an unchanged shard")
+		}
+		m.Set(data.Key, 1000)
+		for _, val := range m.All() {
+			if val != 1000 {
+				t.Errorf("want %v; but got %v", 1000, val)
+			}
+		}
+	})
+	t.Run("zero value", func(t *testing.T) {
+		var m ShardedTree[uint32]
+		for _, p := range r.Perm(len(dataset)) {
+			m.Set(dataset[p].Key, dataset[p].Value)
+		}
+		if m.Len() != len(dataset) {
+			t.Errorf("want %v; but got %v", len(dataset), m.Len())
+		}
+		if got := slices.Collect(m.Values()); !slices.Equal(got, want) {
+			t.Errorf("want %v; but got %v", want, got)
+		}
+		key := dataset[len(dataset)-1].Key
+		if _, found := m.Longest(key); !found {
+			t.Errorf("want %v; but got %v", true, found)
+		}
+	})
+	t.Run("Longest short prefix", func(t *testing.T) {
+		m := NewShardedTree[string](8)
+		m.Set(Key{}, "default")
+		m.Set(BitsKey([]byte{0x80}, 1), "128/1")
+		m.Set(BitsKey([]byte{10, 0, 0, 0}, 8), "10/8")
+		tests := []struct {
+			key  Key
+			want string
+		}{
+			{BitsKey([]byte{10, 1, 2, 3}, 32), "10/8"},
+			{BitsKey([]byte{192, 168, 0, 1}, 32), "128/1"},
+			{BitsKey([]byte{11, 0, 0, 1}, 32), "default"},
+			{BitsKey([]byte{0x80}, 4), "128/1"},
+		}
+		for _, tc := range tests {
+			if val, _ := m.Longest(tc.key); val != tc.want {
+				t.Errorf("Longest(%v): want %v; but got %v", tc.key, tc.want, val)
+			}
+		}
+	})
+}
+
+func TestShardedTreeStress(t *testing.T) {
+	N := 1024
+	// Keys spread over the leading bits, so that all shards are written
+	dataset := setupBitsDataset(rand.New(rand.NewPCG(1, 1)), N)
+	m := NewShardedTree[uint32](4)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 4))
+			for range 5000 {
+				data := dataset[r.IntN(N)]
+				switch r.IntN(4) {
+				case 0:
+					m.Delete(data.Key)
+				case 1:
+					m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+						return data.Value, true
+					})
+				default:
+					m.Set(data.Key, data.Value)
+				}
+			}
+		}()
+	}
+	var rg sync.WaitGroup
+	for w := range 4 {
+		rg.Add(1)
+		go func() {
+			defer rg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 5))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				data := dataset[r.IntN(N)]
+				if val, found := m.Get(data.Key); found && val != data.Value {
+					t.Errorf("want %v; but got %v", data.Value, val)
+					return
+				}
+				if key, val, found := m.LongestPrefix(data.Key); found && !key.Equal(dataset[val].Key) {
+					t.Errorf("unexpected entry %v: %v", key, val)
+					return
+				}
+				// Iteration stays sorted across shards
+				var prev *Key
+				for key, val := range m.Range(Inclusive(data.Key), Unbounded()) {
+					if prev != nil && prev.Compare(key) >= 0 {
+						t.Errorf("%v is not after %v", key, *prev)
+						return
+					}
+					if !key.Equal(dataset[val].Key) {
+						t.Errorf("unexpected entry %v: %v", key, val)
+						return
+					}
+					prev = &key
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	rg.Wait()
+
+	used := 0
+	for i := range m.shards {
+		if m.shards[i].tree.Len() > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("want keys in several shards; but got %v", used)
+	}
+}
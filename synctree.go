@@ -0,0 +1,623 @@
+package critbit
+
+import (
+	"iter"
+)
+
+// syncStripeBits is the number of leading key bits selecting the stripe
+// of a SyncTree.
+const syncStripeBits = 4
+
+// SyncTree is a crit-bit tree that maps Keys to values of type V and
+// is safe for concurrent use by multiple goroutines.
+// The zero value of SyncTree is an empty tree ready for use.
+//
+// SyncTree exposes the Tree API with locks striped by the top-level
+// subtrees of the keyspace: keys are split by their first 4 bits, with
+// missing bits of shorter keys taken as zero, into 16 stripes, each a
+// Tree behind its own read-write mutex. Since the stripe index preserves
+// key order, every key in a stripe sorts before every key in the next.
+// Operations on a single key, such as Get, Set and Delete, lock only
+// the stripe of the key, so modifications of different subtrees do not
+// contend. Operations spanning several stripes, such as Len, Rank,
+// Longest for a key whose prefixes lie in other stripes, or DeleteRange,
+// lock the stripes involved in increasing order and hold them together,
+// so they see and change the tree atomically. Unlike ConcurrentTree,
+// a modification changes the tree in place instead of publishing a new
+// version.
+//
+// Iterators never hold the locks while yielding. When iteration starts,
+// they take an O(1) copy-on-write snapshot of every stripe involved,
+// all at the same point in time, and iterate over them, so each loop
+// sees a consistent view of the tree as of its start. The loop body may
+// freely modify the tree, including deleting the yielded key; such
+// modifications, like those of other goroutines, are not observed by
+// the running iteration.
+//
+// Taking a snapshot shares all nodes of a stripe with it, so the first
+// modification of each path after an iteration copies the path, costing
+// O(d) allocations where d is the depth of the tree. Iterations reuse
+// the last snapshot of a stripe while the stripe is unchanged, so only
+// the first iteration after a modification takes a new one. The last
+// snapshot keeps the nodes it shares alive until it is replaced.
+//
+// The stripes do not keep subtree counts, so Rank, Select and the
+// counting methods visit the keys they count.
+//
+// GetPtr is not provided, since a pointer into the tree could not be
+// used safely outside the lock.
+type SyncTree[V any] struct {
+	stripes [1 << syncStripeBits]shard[V]
+}
+
+// stripe returns the stripe holding key.
+func (t *SyncTree[V]) stripe(key Key) *shard[V] {
+	return &t.stripes[shardOf(key, syncStripeBits)]
+}
+
+// rlock read-locks the stripes from index lo to hi, in order.
+func (t *SyncTree[V]) rlock(lo, hi int) {
+	for i := lo; i <= hi; i++ {
+		t.stripes[i].mu.RLock()
+	}
+}
+
+// runlock releases the read locks taken by rlock.
+func (t *SyncTree[V]) runlock(lo, hi int) {
+	for i := lo; i <= hi; i++ {
+		t.stripes[i].mu.RUnlock()
+	}
+}
+
+// lock locks the stripes from index lo to hi, in order.
+func (t *SyncTree[V]) lock(lo, hi int) {
+	for i := lo; i <= hi; i++ {
+		t.stripes[i].mu.Lock()
+	}
+}
+
+// unlock releases the locks taken by lock.
+func (t *SyncTree[V]) unlock(lo, hi int) {
+	for i := lo; i <= hi; i++ {
+		t.stripes[i].mu.Unlock()
+	}
+}
+
+// snapshots returns copies of the stripes from index lo to hi, all
+// taken at the same point in time, to iterate over without holding the
+// locks. An empty stripe has a nil copy. The copies must not be
+// modified, as they are shared by all iterations until the stripes
+// change. An empty span of stripes, lo > hi, has no copies.
+func (t *SyncTree[V]) snapshots(lo, hi int) []*Tree[V] {
+	if lo > hi {
+		return nil
+	}
+	snaps := make([]*Tree[V], hi-lo+1)
+	t.rlock(lo, hi)
+	ok := true
+	for i := range snaps {
+		if snaps[i], ok = t.stripes[lo+i].cached(); !ok {
+			break
+		}
+	}
+	t.runlock(lo, hi)
+	if ok {
+		return snaps
+	}
+
+	// Clone modifies the trees, so it needs the exclusive locks
+	t.lock(lo, hi)
+	defer t.unlock(lo, hi)
+	for i := range snaps {
+		snaps[i] = t.stripes[lo+i].refresh()
+	}
+	return snaps
+}
+
+// Clone returns a copy of the tree as a new SyncTree.
+// See Tree.Clone.
+func (t *SyncTree[V]) Clone() *SyncTree[V] {
+	c := new(SyncTree[V])
+	for i, snap := range t.snapshots(0, len(t.stripes)-1) {
+		if snap != nil {
+			c.stripes[i].tree = *snap.fork()
+		}
+	}
+	return c
+}
+
+// Freeze returns an immutable snapshot of the tree.
+//
+// The stripes are separate trees, so unlike Tree.Freeze, Freeze builds
+// a new tree from a consistent snapshot of them.
+//
+// Time complexity: O(n*k) where n is the number of key-value pairs.
+func (t *SyncTree[V]) Freeze() *ImmutableTree[V] {
+	frozen := new(ImmutableTree[V])
+	for _, snap := range t.snapshots(0, len(t.stripes)-1) {
+		if snap == nil {
+			continue
+		}
+		for key, val := range snap.All() {
+			frozen.tree.Set(key, val)
+		}
+	}
+	return frozen
+}
+
+// Len returns the number of key-value pairs in the tree.
+func (t *SyncTree[V]) Len() int {
+	t.rlock(0, len(t.stripes)-1)
+	defer t.runlock(0, len(t.stripes)-1)
+	n := 0
+	for i := range t.stripes {
+		n += t.stripes[i].tree.Len()
+	}
+	return n
+}
+
+// Get retrieves the value associated with the given key.
+// See Tree.Get.
+func (t *SyncTree[V]) Get(key Key) (V, bool) {
+	s := t.stripe(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tree.Get(key)
+}
+
+// Set inserts a key-value pair into the tree or updates the value
+// if the key already exists.
+func (t *SyncTree[V]) Set(key Key, value V) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Set(key, value)
+}
+
+// Swap sets the value for the key and returns the previous value.
+// See Tree.Swap.
+func (t *SyncTree[V]) Swap(key Key, value V) (old V, replaced bool) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.Swap(key, value)
+}
+
+// SetIfAbsent inserts the key-value pair only if the key does not
+// already exist. See Tree.SetIfAbsent.
+func (t *SyncTree[V]) SetIfAbsent(key Key, value V) (actual V, inserted bool) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.SetIfAbsent(key, value)
+}
+
+// Update performs an atomic read-modify-write of the value for the key.
+// See Tree.Update. fn is called with the stripe of the key locked and
+// must not access the tree.
+func (t *SyncTree[V]) Update(key Key, fn func(old V, exists bool) (V, bool)) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Update(key, fn)
+}
+
+// Delete removes the key-value pair with the given key from the tree.
+// If the key does not exist, Delete is a no-op.
+func (t *SyncTree[V]) Delete(key Key) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Delete(key)
+}
+
+// LoadAndDelete removes the key-value pair with the given key from the
+// tree and returns the removed value. See Tree.LoadAndDelete.
+func (t *SyncTree[V]) LoadAndDelete(key Key) (V, bool) {
+	s := t.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.LoadAndDelete(key)
+}
+
+// DeletePrefix removes all key-value pairs whose keys have p as
+// a prefix. See Tree.DeletePrefix.
+func (t *SyncTree[V]) DeletePrefix(p Key) int {
+	first, last := shardsWithPrefix(p, syncStripeBits)
+	t.lock(first, last)
+	defer t.unlock(first, last)
+	m := 0
+	for i := first; i <= last; i++ {
+		m += t.stripes[i].tree.DeletePrefix(p)
+	}
+	return m
+}
+
+// DeleteRange removes all key-value pairs whose keys lie between lo
+// and hi. See Tree.DeleteRange.
+func (t *SyncTree[V]) DeleteRange(lo, hi Bound) int {
+	first, last := shardsInRange(lo, hi, syncStripeBits)
+	t.lock(first, last)
+	defer t.unlock(first, last)
+	m := 0
+	for i := first; i <= last; i++ {
+		m += t.stripes[i].tree.DeleteRange(lo, hi)
+	}
+	return m
+}
+
+// Longest performs longest prefix matching on the entire tree.
+// See Tree.Longest.
+func (t *SyncTree[V]) Longest(key Key) (V, bool) {
+	_, val, found := t.LongestPrefix(key)
+	return val, found
+}
+
+// LongestPrefix is like Longest but also returns the matched key.
+//
+// Prefixes shorter than the stripe bits may lie in other stripes than
+// key. Only the stripes that can hold prefixes of key are locked, and
+// they are searched from the longest prefix length down.
+func (t *SyncTree[V]) LongestPrefix(key Key) (Key, V, bool) {
+	var buf [syncStripeBits + 1]int
+	idx := prefixShards(buf[:0], key, syncStripeBits)
+	t.rlockEach(idx)
+	defer t.runlockEach(idx)
+	for _, i := range idx {
+		if k, v, found := t.stripes[i].tree.LongestPrefix(key); found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// Shortest performs shortest prefix matching on the entire tree.
+// See Tree.Shortest.
+func (t *SyncTree[V]) Shortest(key Key) (Key, V, bool) {
+	var buf [syncStripeBits + 1]int
+	idx := prefixShards(buf[:0], key, syncStripeBits)
+	t.rlockEach(idx)
+	defer t.runlockEach(idx)
+	for j := len(idx) - 1; j >= 0; j-- {
+		if k, v, found := t.stripes[idx[j]].tree.Shortest(key); found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// rlockEach read-locks the stripes at the indices in idx, which are in
+// decreasing order, in increasing order.
+func (t *SyncTree[V]) rlockEach(idx []int) {
+	for j := len(idx) - 1; j >= 0; j-- {
+		t.stripes[idx[j]].mu.RLock()
+	}
+}
+
+// runlockEach releases the read locks taken by rlockEach.
+func (t *SyncTree[V]) runlockEach(idx []int) {
+	for _, i := range idx {
+		t.stripes[i].mu.RUnlock()
+	}
+}
+
+// Overlaps reports whether any key in the tree overlaps the prefix p.
+// See Tree.Overlaps.
+func (t *SyncTree[V]) Overlaps(p Key) bool {
+	_, last := shardsWithPrefix(p, syncStripeBits)
+	t.rlock(0, last)
+	defer t.runlock(0, last)
+	for i := range last + 1 {
+		if t.stripes[i].tree.Overlaps(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Min returns the smallest key in the tree and its value.
+func (t *SyncTree[V]) Min() (Key, V, bool) {
+	last := len(t.stripes) - 1
+	t.rlock(0, last)
+	defer t.runlock(0, last)
+	for i := range t.stripes {
+		if k, v, found := t.stripes[i].tree.Min(); found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// Max returns the largest key in the tree and its value.
+func (t *SyncTree[V]) Max() (Key, V, bool) {
+	last := len(t.stripes) - 1
+	t.rlock(0, last)
+	defer t.runlock(0, last)
+	for i := last; i >= 0; i-- {
+		if k, v, found := t.stripes[i].tree.Max(); found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// PopMin removes the smallest key from the tree and returns it
+// with its value.
+func (t *SyncTree[V]) PopMin() (Key, V, bool) {
+	return t.pop(0)
+}
+
+// PopMax removes the largest key from the tree and returns it
+// with its value.
+func (t *SyncTree[V]) PopMax() (Key, V, bool) {
+	return t.pop(1)
+}
+
+// pop removes the first key in the given traversal direction from the
+// first non-empty stripe in that direction.
+func (t *SyncTree[V]) pop(dir int) (Key, V, bool) {
+	last := len(t.stripes) - 1
+	t.lock(0, last)
+	defer t.unlock(0, last)
+	for j := range t.stripes {
+		i := j
+		if dir == 1 {
+			i = last - j
+		}
+		if s := &t.stripes[i]; s.tree.Len() > 0 {
+			return s.tree.pop(dir)
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// Ceiling returns the smallest key in the tree that is greater than
+// or equal to key, and its value.
+func (t *SyncTree[V]) Ceiling(key Key) (Key, V, bool) {
+	return t.nearest(key, 0, (*Tree[V]).Ceiling)
+}
+
+// Higher returns the smallest key in the tree that is strictly
+// greater than key, and its value.
+func (t *SyncTree[V]) Higher(key Key) (Key, V, bool) {
+	return t.nearest(key, 0, (*Tree[V]).Higher)
+}
+
+// Floor returns the largest key in the tree that is less than
+// or equal to key, and its value.
+func (t *SyncTree[V]) Floor(key Key) (Key, V, bool) {
+	return t.nearest(key, 1, (*Tree[V]).Floor)
+}
+
+// Lower returns the largest key in the tree that is strictly
+// less than key, and its value.
+func (t *SyncTree[V]) Lower(key Key) (Key, V, bool) {
+	return t.nearest(key, 1, (*Tree[V]).Lower)
+}
+
+// nearest applies fn to the stripe of key and then to the following
+// stripes in the given traversal direction, until it finds a key.
+// The stripes after the stripe of key hold only keys after key, so
+// the first key found is the nearest.
+func (t *SyncTree[V]) nearest(key Key, dir int, fn func(*Tree[V], Key) (Key, V, bool)) (Key, V, bool) {
+	own := shardOf(key, syncStripeBits)
+	lo, hi := own, len(t.stripes)-1
+	if dir == 1 {
+		lo, hi = 0, own
+	}
+	t.rlock(lo, hi)
+	defer t.runlock(lo, hi)
+	for j := range hi - lo + 1 {
+		i := lo + j
+		if dir == 1 {
+			i = hi - j
+		}
+		if k, v, found := fn(&t.stripes[i].tree, key); found {
+			return k, v, true
+		}
+	}
+	return leafEntry[V](nil)
+}
+
+// Rank returns the number of keys in the tree that are less than key.
+func (t *SyncTree[V]) Rank(key Key) int {
+	own := shardOf(key, syncStripeBits)
+	t.rlock(0, own)
+	defer t.runlock(0, own)
+	n := t.stripes[own].tree.Rank(key)
+	for i := range own {
+		n += t.stripes[i].tree.Len()
+	}
+	return n
+}
+
+// Select returns the key at the zero-based position i in
+// lexicographical order, and its value.
+func (t *SyncTree[V]) Select(i int) (Key, V, bool) {
+	last := len(t.stripes) - 1
+	t.rlock(0, last)
+	defer t.runlock(0, last)
+	for j := range t.stripes {
+		s := &t.stripes[j]
+		if n := s.tree.Len(); i >= n {
+			i -= n
+			continue
+		}
+		return s.tree.Select(i)
+	}
+	return leafEntry[V](nil)
+}
+
+// CountRange returns the number of keys in the tree that lie between
+// lo and hi.
+func (t *SyncTree[V]) CountRange(lo, hi Bound) int {
+	first, last := shardsInRange(lo, hi, syncStripeBits)
+	t.rlock(first, last)
+	defer t.runlock(first, last)
+	n := 0
+	for i := first; i <= last; i++ {
+		n += t.stripes[i].tree.CountRange(lo, hi)
+	}
+	return n
+}
+
+// CountPrefix returns the number of keys in the tree that have p as
+// a prefix.
+func (t *SyncTree[V]) CountPrefix(p Key) int {
+	first, last := shardsWithPrefix(p, syncStripeBits)
+	t.rlock(first, last)
+	defer t.runlock(first, last)
+	n := 0
+	for i := first; i <= last; i++ {
+		n += t.stripes[i].tree.CountPrefix(p)
+	}
+	return n
+}
+
+// scan returns an iterator over the stripes from index lo to hi,
+// inclusive, in the given traversal order, over snapshots taken when
+// iteration starts. seq selects what to iterate in each snapshot.
+func (t *SyncTree[V]) scan(lo, hi int, reverse bool, seq func(*Tree[V]) iter.Seq2[Key, V]) iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		snaps := t.snapshots(lo, hi)
+		for j := range snaps {
+			snap := snaps[j]
+			if reverse {
+				snap = snaps[len(snaps)-1-j]
+			}
+			if snap == nil {
+				continue
+			}
+			for key, val := range seq(snap) {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// All returns an iterator over all key-value pairs in the tree
+// in lexicographical order of keys, over a snapshot taken when
+// iteration starts.
+func (t *SyncTree[V]) All() iter.Seq2[Key, V] {
+	return t.scan(0, len(t.stripes)-1, false, (*Tree[V]).All)
+}
+
+// Keys returns an iterator over all keys in the tree
+// in lexicographical order, over a snapshot taken when iteration
+// starts.
+func (t *SyncTree[V]) Keys() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for key := range t.All() {
+			if !yield(key) {
+				break
+			}
+		}
+	}
+}
+
+// Values returns an iterator over all values in the tree in the order
+// corresponding to their keys' lexicographical order, over a snapshot
+// taken when iteration starts.
+func (t *SyncTree[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range t.All() {
+			if !yield(val) {
+				break
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over all key-value pairs in the tree
+// in reverse lexicographical order of keys, over a snapshot taken when
+// iteration starts.
+func (t *SyncTree[V]) Backward() iter.Seq2[Key, V] {
+	return t.scan(0, len(t.stripes)-1, true, (*Tree[V]).Backward)
+}
+
+// KeysBackward returns an iterator over all keys in the tree
+// in reverse lexicographical order, over a snapshot taken when
+// iteration starts.
+func (t *SyncTree[V]) KeysBackward() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for key := range t.Backward() {
+			if !yield(key) {
+				break
+			}
+		}
+	}
+}
+
+// ValuesBackward returns an iterator over all values in the tree in the
+// order corresponding to their keys' reverse lexicographical order,
+// over a snapshot taken when iteration starts.
+func (t *SyncTree[V]) ValuesBackward() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range t.Backward() {
+			if !yield(val) {
+				break
+			}
+		}
+	}
+}
+
+// Range returns an iterator over the key-value pairs whose keys lie
+// between lo and hi, in lexicographical order of keys, over a snapshot
+// taken when iteration starts.
+func (t *SyncTree[V]) Range(lo, hi Bound) iter.Seq2[Key, V] {
+	first, last := shardsInRange(lo, hi, syncStripeBits)
+	return t.scan(first, last, false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.Range(lo, hi)
+	})
+}
+
+// RangeBackward returns an iterator over the key-value pairs whose keys
+// lie between lo and hi, in reverse lexicographical order of keys,
+// over a snapshot taken when iteration starts.
+func (t *SyncTree[V]) RangeBackward(lo, hi Bound) iter.Seq2[Key, V] {
+	first, last := shardsInRange(lo, hi, syncStripeBits)
+	return t.scan(first, last, true, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.RangeBackward(lo, hi)
+	})
+}
+
+// WithPrefix returns an iterator over the key-value pairs whose keys
+// have p as a prefix, in lexicographical order of keys, over a snapshot
+// taken when iteration starts.
+func (t *SyncTree[V]) WithPrefix(p Key) iter.Seq2[Key, V] {
+	first, last := shardsWithPrefix(p, syncStripeBits)
+	return t.scan(first, last, false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.WithPrefix(p)
+	})
+}
+
+// WithPrefixBackward returns an iterator over the key-value pairs whose
+// keys have p as a prefix, in reverse lexicographical order of keys,
+// over a snapshot taken when iteration starts.
+func (t *SyncTree[V]) WithPrefixBackward(p Key) iter.Seq2[Key, V] {
+	first, last := shardsWithPrefix(p, syncStripeBits)
+	return t.scan(first, last, true, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.WithPrefixBackward(p)
+	})
+}
+
+// Prefixes returns an iterator over the key-value pairs whose keys are
+// prefixes of the given key, from the shortest to the longest, over
+// a snapshot taken when iteration starts.
+func (t *SyncTree[V]) Prefixes(key Key) iter.Seq2[Key, V] {
+	return t.scan(0, shardOf(key, syncStripeBits), false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.Prefixes(key)
+	})
+}
+
+// Overlapping returns an iterator over the key-value pairs whose keys
+// overlap the prefix p, over a snapshot taken when iteration starts.
+// See Tree.Overlapping.
+func (t *SyncTree[V]) Overlapping(p Key) iter.Seq2[Key, V] {
+	_, last := shardsWithPrefix(p, syncStripeBits)
+	return t.scan(0, last, false, func(s *Tree[V]) iter.Seq2[Key, V] {
+		return s.Overlapping(p)
+	})
+}
@@ -0,0 +1,331 @@
+package critbit
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSyncTree(t *testing.T) {
+	N := 256
+	dataset := setupDataset(N)
+	var m SyncTree[uint32]
+
+	t.Run("Set Get", func(t *testing.T) {
+		for _, data := range dataset {
+			m.Set(data.Key, data.Value)
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+		for _, data := range dataset {
+			val, found := m.Get(data.Key)
+			if !found {
+				t.Fatalf("%x not found", data.Key)
+			}
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+		}
+	})
+	t.Run("All", func(t *testing.T) {
+		i := 0
+		for key, val := range m.All() {
+			data := dataset[i]
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+			i++
+		}
+		if i != N {
+			t.Errorf("want %v; but got %v", N, i)
+		}
+	})
+	t.Run("Delete during iteration", func(t *testing.T) {
+		c := m.Clone()
+		i := 0
+		for key := range c.Keys() {
+			data := dataset[i]
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			// Deleting the current and the next keys does not
+			// affect the running iteration
+			c.Delete(key)
+			if i+1 < N {
+				c.Delete(dataset[i+1].Key)
+			}
+			i++
+		}
+		if i != N {
+			t.Errorf("want %v; but got %v", N, i)
+		}
+		if c.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, c.Len())
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+	})
+	t.Run("Set during iteration", func(t *testing.T) {
+		i := 0
+		for key, val := range m.Backward() {
+			m.Set(key, val+1)
+			i++
+		}
+		if i != N {
+			t.Errorf("want %v; but got %v", N, i)
+		}
+		for _, data := range dataset {
+			val, _ := m.Get(data.Key)
+			if val != data.Value+1 {
+				t.Errorf("want %v; but got %v", data.Value+1, val)
+			}
+		}
+	})
+	t.Run("snapshot reuse", func(t *testing.T) {
+		data := dataset[0]
+		s := m.stripe(data.Key)
+		for range m.All() {
+		}
+		snap := s.snap
+		for range m.All() {
+		}
+		if s.snap != snap {
+			t.Errorf("snapshot taken again for an unchanged tree")
+		}
+		// Any modification, even of a value, requires a new snapshot
+		m.Set(data.Key, 1000)
+		for key, val := range m.All() {
+			if key.Equal(data.Key) && val != 1000 {
+				t.Errorf("want %v; but got %v", 1000, val)
+			}
+		}
+		m.Set(data.Key, data.Value+1)
+	})
+	t.Run("Freeze", func(t *testing.T) {
+		frozen := m.Freeze()
+		m.DeletePrefix(Key{})
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+		if frozen.Len() != N {
+			t.Errorf("want %v; but got %v", N, frozen.Len())
+		}
+	})
+}
+
+func TestSyncTreeStress(t *testing.T) {
+	N := 1024
+	// Keys spread over the leading bits, so that all stripes are written
+	dataset := setupBitsDataset(rand.New(rand.NewPCG(1, 1)), N)
+	var m SyncTree[uint32]
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 2))
+			for range 5000 {
+				data := dataset[r.IntN(N)]
+				switch r.IntN(6) {
+				case 0:
+					m.Delete(data.Key)
+				case 1:
+					m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+						return data.Value, true
+					})
+				case 2:
+					m.DeleteRange(Inclusive(data.Key), Exclusive(dataset[min(int(data.Value)+4, N-1)].Key))
+				case 3:
+					m.PopMin()
+				default:
+					m.Set(data.Key, data.Value)
+				}
+			}
+		}()
+	}
+	var rg sync.WaitGroup
+	for w := range 4 {
+		rg.Add(1)
+		go func() {
+			defer rg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 3))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				data := dataset[r.IntN(N)]
+				if val, found := m.Get(data.Key); found && val != data.Value {
+					t.Errorf("want %v; but got %v", data.Value, val)
+					return
+				}
+				if key, val, found := m.LongestPrefix(data.Key); found && !key.Equal(dataset[val].Key) {
+					t.Errorf("unexpected entry %v: %v", key, val)
+					return
+				}
+				_ = m.Rank(data.Key)
+				// Each iteration sees a sorted, consistent snapshot
+				var prev *Key
+				for key, val := range m.Range(Inclusive(data.Key), Unbounded()) {
+					if prev != nil && prev.Compare(key) >= 0 {
+						t.Errorf("%v is not after %v", key, *prev)
+						return
+					}
+					if val != dataset[val].Value || !key.Equal(dataset[val].Key) {
+						t.Errorf("unexpected entry %v: %v", key, val)
+						return
+					}
+					prev = &key
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	rg.Wait()
+}
+
+func TestSyncTreeStripes(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	// Counted, unlike the stripes, so that the counting methods are
+	// checked against the other implementation
+	ref := NewCountedTree[uint32]()
+	var m SyncTree[uint32]
+	for _, p := range r.Perm(len(dataset)) {
+		ref.Set(dataset[p].Key, dataset[p].Value)
+		m.Set(dataset[p].Key, dataset[p].Value)
+	}
+	used := 0
+	for i := range m.stripes {
+		if m.stripes[i].tree.Len() > 0 {
+			used++
+		}
+	}
+	if used < len(m.stripes) {
+		t.Fatalf("want keys in all %v stripes; but got %v", len(m.stripes), used)
+	}
+
+	// same compares the entries yielded by two iterators
+	same := func(t *testing.T, name string, want, got iter.Seq2[Key, uint32]) {
+		t.Helper()
+		var w, g []uint32
+		for _, val := range want {
+			w = append(w, val)
+		}
+		for _, val := range got {
+			g = append(g, val)
+		}
+		if !slices.Equal(g, w) {
+			t.Fatalf("%v: want %v; but got %v", name, w, g)
+		}
+	}
+	// entry compares the results of two lookups
+	entry := func(t *testing.T, name string, wk Key, wv uint32, wf bool, gk Key, gv uint32, gf bool) {
+		t.Helper()
+		if gf != wf || !gk.Equal(wk) || gv != wv {
+			t.Fatalf("%v: want %v %v %v; but got %v %v %v", name, wk, wv, wf, gk, gv, gf)
+		}
+	}
+
+	t.Run("lookups", func(t *testing.T) {
+		if m.Len() != ref.Len() {
+			t.Errorf("want %v; but got %v", ref.Len(), m.Len())
+		}
+		k, v, f := ref.Min()
+		gk, gv, gf := m.Min()
+		entry(t, "Min", k, v, f, gk, gv, gf)
+		k, v, f = ref.Max()
+		gk, gv, gf = m.Max()
+		entry(t, "Max", k, v, f, gk, gv, gf)
+		for i := -1; i <= len(dataset); i++ {
+			k, v, f := ref.Select(i)
+			gk, gv, gf := m.Select(i)
+			entry(t, "Select", k, v, f, gk, gv, gf)
+		}
+		for range 1000 {
+			key := randomBound(r, dataset).key
+			for _, fn := range []struct {
+				name     string
+				ref, got func(Key) (Key, uint32, bool)
+			}{
+				{"Ceiling", ref.Ceiling, m.Ceiling},
+				{"Higher", ref.Higher, m.Higher},
+				{"Floor", ref.Floor, m.Floor},
+				{"Lower", ref.Lower, m.Lower},
+				{"LongestPrefix", ref.LongestPrefix, m.LongestPrefix},
+				{"Shortest", ref.Shortest, m.Shortest},
+			} {
+				k, v, f := fn.ref(key)
+				gk, gv, gf := fn.got(key)
+				entry(t, fn.name, k, v, f, gk, gv, gf)
+			}
+			if got, want := m.Rank(key), ref.Rank(key); got != want {
+				t.Fatalf("Rank(%v): want %v; but got %v", key, want, got)
+			}
+			if got, want := m.CountPrefix(key), ref.CountPrefix(key); got != want {
+				t.Fatalf("CountPrefix(%v): want %v; but got %v", key, want, got)
+			}
+			if got, want := m.Overlaps(key), ref.Overlaps(key); got != want {
+				t.Fatalf("Overlaps(%v): want %v; but got %v", key, want, got)
+			}
+			lo := randomBound(r, dataset)
+			hi := randomBound(r, dataset)
+			if got, want := m.CountRange(lo, hi), ref.CountRange(lo, hi); got != want {
+				t.Fatalf("CountRange(%v, %v): want %v; but got %v", lo, hi, want, got)
+			}
+		}
+	})
+	t.Run("iterators", func(t *testing.T) {
+		same(t, "All", ref.All(), m.All())
+		same(t, "Backward", ref.Backward(), m.Backward())
+		for range 200 {
+			key := randomBound(r, dataset).key
+			lo := randomBound(r, dataset)
+			hi := randomBound(r, dataset)
+			same(t, "Range", ref.Range(lo, hi), m.Range(lo, hi))
+			same(t, "RangeBackward", ref.RangeBackward(lo, hi), m.RangeBackward(lo, hi))
+			same(t, "WithPrefix", ref.WithPrefix(key), m.WithPrefix(key))
+			same(t, "WithPrefixBackward", ref.WithPrefixBackward(key), m.WithPrefixBackward(key))
+			same(t, "Prefixes", ref.Prefixes(key), m.Prefixes(key))
+			same(t, "Overlapping", ref.Overlapping(key), m.Overlapping(key))
+		}
+		same(t, "Freeze", ref.All(), m.Freeze().All())
+		same(t, "Clone", ref.All(), m.Clone().All())
+	})
+	t.Run("deletions", func(t *testing.T) {
+		for range 100 {
+			switch r.IntN(3) {
+			case 0:
+				lo := randomBound(r, dataset)
+				hi := randomBound(r, dataset)
+				if got, want := m.DeleteRange(lo, hi), ref.DeleteRange(lo, hi); got != want {
+					t.Fatalf("DeleteRange(%v, %v): want %v; but got %v", lo, hi, want, got)
+				}
+			case 1:
+				p := randomBound(r, dataset).key
+				if got, want := m.DeletePrefix(p), ref.DeletePrefix(p); got != want {
+					t.Fatalf("DeletePrefix(%v): want %v; but got %v", p, want, got)
+				}
+			default:
+				k, v, f := ref.PopMin()
+				gk, gv, gf := m.PopMin()
+				entry(t, "PopMin", k, v, f, gk, gv, gf)
+				k, v, f = ref.PopMax()
+				gk, gv, gf = m.PopMax()
+				entry(t, "PopMax", k, v, f, gk, gv, gf)
+			}
+			same(t, "All", ref.All(), m.All())
+		}
+	})
+}
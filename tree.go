@@ -8,6 +8,7 @@ package critbit
 
 import (
 	"iter"
+	"unsafe"
 )
 
 // Leaf represents a terminal node in the crit-bit tree containing
@@ -15,6 +16,8 @@ import (
 type Leaf[V any] struct {
 	Key   Key
 	Value V
+	// owner is the tree allowed to modify the leaf in place
+	owner *owner
 }
 
 // Inner represents an internal node in the crit-bit tree.
@@ -27,6 +30,42 @@ type Inner[V any] struct {
 	// child contains exactly two children:
 	// [0] for left, [1] for right
 	child [2]Node[V]
+	// owner is the tree allowed to modify the node in place
+	owner *owner
+}
+
+// countedInner is an internal node of a tree keeping subtree counts.
+// Such a tree allocates every internal node as a countedInner and
+// links it by its Inner field, which comes first, so that the count
+// is found at the same address as the node.
+type countedInner[V any] struct {
+	Inner[V]
+	count int // number of leaves in the subtree
+}
+
+// counter returns a pointer to the number of leaves in the subtree of
+// the node, or nil if the tree of the node does not keep counts.
+func (inner *Inner[V]) counter() *int {
+	if inner.owner == nil || !inner.owner.counted {
+		return nil
+	}
+	return &(*countedInner[V])(unsafe.Pointer(inner)).count
+}
+
+// owner identifies the tree allowed to modify a node in place.
+// Nodes reachable from a tree and its clones are owned by none of
+// them, and are copied by the first tree that modifies them.
+type owner struct {
+	// counted reports whether the tree keeps subtree counts.
+	// It also gives owner a non-zero size, so that each owner has
+	// a distinct address.
+	counted bool
+}
+
+// next returns a new owner for a tree derived from the tree owned by o,
+// which keeps subtree counts if that tree does.
+func (o *owner) next() *owner {
+	return &owner{counted: o != nil && o.counted}
 }
 
 // Node represents either an internal node or a leaf node
@@ -42,40 +81,107 @@ type Node[V any] struct {
 // given key.
 // This is particularly useful for applications like IP routing.
 //
+// Time complexity: O(k) where k is the length of the key in bits.
+//
 // Returns the value associated with the longest matching prefix and true,
 // or the zero value and false if no prefix match is found.
 func (n Node[V]) Longest(key Key) (V, bool) {
+	leaf := n.longest(key)
+	if leaf == nil {
+		var val V
+		return val, false
+	}
+	return leaf.Value, true
+}
+
+// len returns the number of leaves in the subtree.
+// It runs in O(1) time in a tree keeping subtree counts, and counts the
+// leaves otherwise.
+func (n Node[V]) len() int {
 	if inner := n.Inner; inner != nil {
-		// Internal node: choose direction and recurse
-		dir := key.Direction(inner.bit)
-		val, found := inner.child[dir].Longest(key)
-		if found {
-			return val, found
-		}
-		// If no match in preferred direction,
-		// try the other direction
-		if dir == 1 {
-			return inner.child[0].Longest(key)
+		if c := inner.counter(); c != nil {
+			return *c
 		}
-	} else if leaf := n.Leaf; leaf != nil {
-		// Leaf node: check if this key is a prefix of the
-		// search key
-		if key.HasPrefix(leaf.Key) {
-			return leaf.Value, true
+		return inner.child[0].len() + inner.child[1].len()
+	}
+	if n.Leaf != nil {
+		return 1
+	}
+	return 0
+}
+
+// empty reports whether the subtree has no leaves.
+func (n Node[V]) empty() bool {
+	return n.Inner == nil && n.Leaf == nil
+}
+
+// find follows the path through the subtree according to the given key
+// and returns the leaf that would contain the key if it exists.
+// Returns nil if the subtree is empty.
+func (n Node[V]) find(key Key) *Leaf[V] {
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
 		}
+		n = inner.child[key.Direction(inner.bit)]
 	}
-	var val V
-	return val, false
+	return n.Leaf
 }
 
 // Tree represents a crit-bit tree that maps Keys to values of type V.
 // The zero value of Tree is an empty tree ready for use.
+// Use NewCountedTree for a tree keeping subtree counts, which speed up
+// the order statistics.
 //
 // Tree is not safe for concurrent access. Use external synchronization
-// if the tree needs to be accessed from multiple goroutines.
+// if the tree needs to be accessed from multiple goroutines, or use
+// SyncTree or ConcurrentTree.
 type Tree[V any] struct {
-	nums int     // number of key-value pairs in the tree
-	root Node[V] // root node of the tree
+	nums  int     // number of key-value pairs in the tree
+	root  Node[V] // root node of the tree
+	owner *owner  // owner of the nodes modifiable in place
+	mods  int     // number of changes to the nodes, checked by iterators
+}
+
+// NewCountedTree returns an empty Tree that keeps the number of leaves
+// under every internal node, maintained by Set, Delete and the other
+// updates. The counts let Rank, Select, CountRange and CountPrefix run
+// in O(k) time, and DeletePrefix and DeleteRange count the removed
+// pairs without visiting them, at the cost of one more word per
+// internal node. Clones and frozen copies of the tree keep counts too.
+func NewCountedTree[V any]() *Tree[V] {
+	return &Tree[V]{owner: &owner{counted: true}}
+}
+
+// Clone returns a copy of the tree.
+//
+// Clone runs in O(1) time: the copy shares all nodes with the original,
+// and each tree copies the nodes on a path lazily the first time it
+// modifies them. Modifying one tree through its methods never affects
+// the other.
+//
+// Clone invalidates the pointers previously returned by GetPtr and
+// GetOrInsertPtr on t, as t no longer modifies the shared leaves in
+// place. Such a pointer still refers to a leaf shared by both trees,
+// so writing through it changes the value in both: after
+// p := t.GetPtr(k) and c := t.Clone(), *p = v makes both t.Get(k) and
+// c.Get(k) return v. Call GetPtr again after Clone instead.
+func (t *Tree[V]) Clone() *Tree[V] {
+	// Neither tree owns the shared nodes from now on
+	t.owner = t.owner.next()
+	return t.fork()
+}
+
+// fork returns a new tree sharing all nodes with t without owning any
+// of them. Unlike Clone, it leaves t unchanged, which is enough when t
+// is never modified again.
+func (t *Tree[V]) fork() *Tree[V] {
+	return &Tree[V]{
+		nums:  t.nums,
+		root:  t.root,
+		owner: t.owner.next(),
+	}
 }
 
 // Len returns the number of key-value pairs in the tree.
@@ -100,32 +206,81 @@ func (t *Tree[V]) Get(key Key) (V, bool) {
 	return leaf.Value, true
 }
 
-// Set inserts a key-value pair into the tree or updates the value
-// if the key already exists.
+// GetPtr returns a pointer to the value associated with the given key,
+// allowing the value to be read and modified in place without copying.
+// Returns nil if the key is not found.
+//
+// The pointer refers to the value stored in the key's leaf. It stays
+// valid, and observes later Set, Swap and Update calls for the key,
+// until the key is deleted or the tree is cloned, whichever comes
+// first. After the key is removed from the tree, the pointer still
+// refers to the old value, but writes through it no longer affect
+// the tree.
+//
+// Since the pointer allows modification, GetPtr copies the path to the
+// leaf if it is shared with a clone. After a call to Clone, the leaf
+// is shared by both trees, and the next modification of the key in
+// either tree moves it to a copy. A pointer obtained before Clone
+// therefore neither observes later changes nor may be written through;
+// call GetPtr again to get a valid one.
 //
 // Time complexity: O(k) where k is the length of the key in bits.
-func (t *Tree[V]) Set(key Key, value V) {
+func (t *Tree[V]) GetPtr(key Key) *V {
 	leaf := t.findLeaf(key)
 	if leaf == nil {
-		// Tree is empty, create first leaf
-		t.root.Leaf = &Leaf[V]{
-			Key:   key,
-			Value: value,
-		}
-		t.nums++
-		return
+		return nil
 	}
+	if !leaf.Key.Equal(key) {
+		return nil
+	}
+	if leaf.owner != t.owner {
+		leaf = t.ownLeaf(key)
+	}
+	return &leaf.Value
+}
 
-	bit := leaf.Key.Critbit(key)
-	if bit == -1 {
-		// Key already exists, replace value
+// GetOrInsertPtr is like GetPtr, but inserts the key with the zero
+// value of V if it does not exist, so the result is never nil.
+// The same validity rules as GetPtr apply.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) GetOrInsertPtr(key Key) *V {
+	leaf, _ := t.upsert(key)
+	return &leaf.Value
+}
+
+// Set inserts a key-value pair into the tree or updates the value
+// if the key already exists.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Set(key Key, value V) {
+	leaf, _ := t.upsert(key)
+	leaf.Value = value
+}
+
+// Swap sets the value for the key and returns the previous value.
+// The replaced result reports whether the key was already present;
+// if not, old is the zero value of V.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Swap(key Key, value V) (old V, replaced bool) {
+	leaf, inserted := t.upsert(key)
+	old = leaf.Value
+	leaf.Value = value
+	return old, !inserted
+}
+
+// SetIfAbsent inserts the key-value pair only if the key does not
+// already exist. It returns the value stored for the key afterwards,
+// and whether the given value was inserted.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) SetIfAbsent(key Key, value V) (actual V, inserted bool) {
+	leaf, inserted := t.upsert(key)
+	if inserted {
 		leaf.Value = value
-		return
 	}
-
-	// Insert new internal node at the appropriate position
-	n := t.findNode(key, bit)
-	t.insertNode(n, &Leaf[V]{Key: key, Value: value}, bit)
+	return leaf.Value, inserted
 }
 
 // Delete removes the key-value pair with the given key from the tree.
@@ -133,8 +288,17 @@ func (t *Tree[V]) Set(key Key, value V) {
 //
 // Time complexity: O(k) where k is the length of the key in bits.
 func (t *Tree[V]) Delete(key Key) {
-	var p *Node[V] // parent of current node
-	var dir int    // direction taken from parent
+	t.LoadAndDelete(key)
+}
+
+// LoadAndDelete removes the key-value pair with the given key from the
+// tree and returns the removed value.
+// Returns the zero value of V and false if the key does not exist.
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) LoadAndDelete(key Key) (V, bool) {
+	var p *Inner[V] // parent of current node
+	var dir int     // direction taken from parent
 	n := &t.root
 
 	// Find the leaf node and its parent
@@ -143,28 +307,86 @@ func (t *Tree[V]) Delete(key Key) {
 		if inner == nil {
 			break
 		}
-		p = n
+		p = inner
 		dir = key.Direction(inner.bit)
 		n = &inner.child[dir]
 	}
 
+	var val V
 	leaf := n.Leaf
 	if leaf == nil {
-		return // key not found
+		return val, false // key not found
 	}
 	if !leaf.Key.Equal(key) {
-		return // key not found
+		return val, false // key not found
 	}
 
 	// Remove the node
-	if p == nil {
-		// Removing the only node in the tree
-		t.root = Node[V]{}
-	} else {
-		// Replace parent with sibling
-		*p = p.Inner.child[dir^1]
+	t.detach(key, p, dir, 1)
+	return leaf.Value, true
+}
+
+// Update performs a read-modify-write of the value for the key.
+// It calls fn with the current value and whether the key exists
+// (the zero value of V and false if it does not). fn returns the new
+// value and whether to keep the key: the key is then inserted or its
+// value replaced, or the key is deleted if keep is false.
+//
+// The leaf is located only once, so Update avoids the second traversal
+// of a Get followed by a Set or Delete.
+//
+// Example:
+//
+//	// Increment a counter
+//	tree.Update(key, func(n int, _ bool) (int, bool) {
+//	    return n + 1, true
+//	})
+//
+// Time complexity: O(k) where k is the length of the key in bits.
+func (t *Tree[V]) Update(key Key, fn func(old V, exists bool) (V, bool)) {
+	var p *Inner[V] // parent of current node
+	var dir int     // direction taken from parent
+	n := &t.root
+
+	// Find the leaf node and its parent
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		p = inner
+		dir = key.Direction(inner.bit)
+		n = &inner.child[dir]
+	}
+
+	leaf := n.Leaf
+	if leaf != nil && leaf.Key.Equal(key) {
+		val, keep := fn(leaf.Value, true)
+		if keep {
+			if leaf.owner != t.owner {
+				leaf = t.ownLeaf(key)
+			}
+			leaf.Value = val
+		} else {
+			t.detach(key, p, dir, 1)
+		}
+		return
+	}
+
+	var zero V
+	val, keep := fn(zero, false)
+	if !keep {
+		return
+	}
+	if leaf == nil {
+		// Tree is empty, create first leaf
+		t.root.Leaf = t.newLeaf(key, val)
+		t.nums++
+		t.mods++
+		return
 	}
-	t.nums--
+	// Insert new internal node at the appropriate position
+	t.insertLeaf(t.newLeaf(key, val), leaf.Key.Critbit(key))
 }
 
 // Longest performs longest prefix matching on the entire tree.
@@ -179,6 +401,15 @@ func (t *Tree[V]) Longest(key Key) (V, bool) {
 	return t.root.Longest(key)
 }
 
+// LongestPrefix is like Longest but also returns the matched key,
+// so that callers can tell which prefix matched.
+//
+// Returns the longest matching prefix, its value and true,
+// or false if no prefix match is found.
+func (t *Tree[V]) LongestPrefix(key Key) (Key, V, bool) {
+	return leafEntry(t.root.longest(key))
+}
+
 // Keys returns an iterator over all keys in the tree
 // in lexicographical order.
 // The iterator follows Go 1.23+ iterator conventions and can be used with
@@ -191,12 +422,7 @@ func (t *Tree[V]) Longest(key Key) (V, bool) {
 //	}
 func (t *Tree[V]) Keys() iter.Seq[Key] {
 	return func(yield func(Key) bool) {
-		s := NewScanner(t.root, false)
-		for {
-			leaf := s.Scan()
-			if leaf == nil {
-				break
-			}
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), false) {
 			if !yield(leaf.Key) {
 				break
 			}
@@ -215,12 +441,7 @@ func (t *Tree[V]) Keys() iter.Seq[Key] {
 //	}
 func (t *Tree[V]) Values() iter.Seq[V] {
 	return func(yield func(V) bool) {
-		s := NewScanner(t.root, false)
-		for {
-			leaf := s.Scan()
-			if leaf == nil {
-				break
-			}
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), false) {
 			if !yield(leaf.Value) {
 				break
 			}
@@ -232,6 +453,12 @@ func (t *Tree[V]) Values() iter.Seq[V] {
 // in lexicographical order of keys.
 // The iterator follows Go 1.23+ iterator conventions.
 //
+// The loop body may modify the tree, such as deleting the yielded key.
+// The iteration then continues in the modified tree after the last key
+// yielded, so it never yields a key that has been removed. The same
+// holds for the other iterators of Tree, including Range, WithPrefix,
+// Prefixes and Overlapping.
+//
 // Example:
 //
 //	for key, value := range tree.All() {
@@ -239,23 +466,107 @@ func (t *Tree[V]) Values() iter.Seq[V] {
 //	}
 func (t *Tree[V]) All() iter.Seq2[Key, V] {
 	return func(yield func(Key, V) bool) {
-		s := NewScanner(t.root, false)
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), false) {
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// KeysBackward returns an iterator over all keys in the tree
+// in reverse lexicographical order.
+func (t *Tree[V]) KeysBackward() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), true) {
+			if !yield(leaf.Key) {
+				break
+			}
+		}
+	}
+}
+
+// ValuesBackward returns an iterator over all values in the tree in the
+// order corresponding to their keys' reverse lexicographical order.
+func (t *Tree[V]) ValuesBackward() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), true) {
+			if !yield(leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over all key-value pairs in the tree
+// in reverse lexicographical order of keys.
+//
+// Example:
+//
+//	for key, value := range tree.Backward() {
+//	    fmt.Printf("Key: %v, Value: %v\n", key, value)
+//	}
+func (t *Tree[V]) Backward() iter.Seq2[Key, V] {
+	return func(yield func(Key, V) bool) {
+		for leaf := range t.leaves(t.rootNode, Unbounded(), Unbounded(), true) {
+			if !yield(leaf.Key, leaf.Value) {
+				break
+			}
+		}
+	}
+}
+
+// leaves returns an iterator over the leaves of the subtree returned by
+// root, from start to end in the given traversal order.
+//
+// The loop body may modify the tree. If it does, the traversal resumes
+// in the modified tree right after the last leaf yielded, rather than
+// from the stale nodes on the scanner stack. Removed keys that were not
+// reached yet are not yielded, and inserted keys ahead are.
+func (t *Tree[V]) leaves(root func() Node[V], start, end Bound, reverse bool) iter.Seq[*Leaf[V]] {
+	return func(yield func(*Leaf[V]) bool) {
+		s := NewScanner(root(), reverse)
+		s.seekBound(start)
+		s.SetEnd(end)
+		mods := t.mods
 		for {
 			leaf := s.Scan()
 			if leaf == nil {
 				break
 			}
-			if !yield(leaf.Key, leaf.Value) {
+			if !yield(leaf) {
 				break
 			}
+			if t.mods != mods {
+				mods = t.mods
+				s.root = root()
+				s.SeekAfter(leaf.Key)
+			}
 		}
 	}
 }
 
-// findNode locates the position where a new internal node with the given
-// critical bit should be inserted. It returns a pointer to the node
-// that should become a child of the new internal node.
-func (t *Tree[V]) findNode(key Key, bit int) *Node[V] {
+// rootNode returns the root node of the tree.
+func (t *Tree[V]) rootNode() Node[V] {
+	return t.root
+}
+
+// Scanner returns a new Scanner over the tree.
+// If reverse is true, the scanner will traverse in reverse
+// lexicographical order.
+func (t *Tree[V]) Scanner(reverse bool) *Scanner[V] {
+	return NewScanner(t.root, reverse)
+}
+
+// insertLeaf inserts a new leaf into a non-empty tree, where bit is the
+// critical bit between the leaf's key and the tree.
+//
+// It descends to the position where a new internal node with the given
+// critical bit belongs, making the internal nodes on the way modifiable
+// and counting the new leaf in each of them, and then inserts the
+// internal node there with insertNode.
+func (t *Tree[V]) insertLeaf(leaf *Leaf[V], bit int) {
+	key := leaf.Key
 	n := &t.root
 	for {
 		inner := n.Inner
@@ -267,12 +578,73 @@ func (t *Tree[V]) findNode(key Key, bit int) *Node[V] {
 		if inner.bit > bit {
 			break
 		}
+		inner = t.own(n).Inner
+		if c := inner.counter(); c != nil {
+			*c++
+		}
 		dir := key.Direction(inner.bit)
 		n = &inner.child[dir]
 	}
+	t.insertNode(n, leaf, bit)
+}
+
+// ownLeaf follows the path through the tree according to the given key
+// like findLeaf, making every node on the way modifiable in place,
+// and returns the leaf at the end of the path.
+func (t *Tree[V]) ownLeaf(key Key) *Leaf[V] {
+	n := t.own(&t.root)
+	for {
+		inner := n.Inner
+		if inner == nil {
+			break
+		}
+		dir := key.Direction(inner.bit)
+		n = t.own(&inner.child[dir])
+	}
+	return n.Leaf
+}
+
+// own makes the node at n modifiable in place by the tree, replacing
+// it with a copy owned by the tree if it is shared with a clone.
+// n must be the root or a child of a node owned by the tree.
+// It returns n.
+func (t *Tree[V]) own(n *Node[V]) *Node[V] {
+	if inner := n.Inner; inner != nil {
+		if inner.owner != t.owner {
+			c := t.newInner(inner.bit)
+			c.child = inner.child
+			if p := inner.counter(); p != nil {
+				*c.counter() = *p
+			}
+			n.Inner = c
+			t.mods++
+		}
+	} else if leaf := n.Leaf; leaf != nil {
+		if leaf.owner != t.owner {
+			c := *leaf
+			c.owner = t.owner
+			n.Leaf = &c
+			t.mods++
+		}
+	}
 	return n
 }
 
+// newInner creates an internal node owned by the tree, with room for
+// the subtree count if the tree keeps counts.
+func (t *Tree[V]) newInner(bit int) *Inner[V] {
+	if t.owner != nil && t.owner.counted {
+		c := &countedInner[V]{Inner: Inner[V]{bit: bit, owner: t.owner}}
+		return &c.Inner
+	}
+	return &Inner[V]{bit: bit, owner: t.owner}
+}
+
+// newLeaf creates a leaf owned by the tree.
+func (t *Tree[V]) newLeaf(key Key, value V) *Leaf[V] {
+	return &Leaf[V]{Key: key, Value: value, owner: t.owner}
+}
+
 // findLeaf follows the path through the tree according to the given key
 // and returns the leaf node that would contain the key if it exists.
 // Returns nil if the tree is empty.
@@ -289,16 +661,76 @@ func (t *Tree[V]) findLeaf(key Key) *Leaf[V] {
 	return n.Leaf
 }
 
+// detach removes the subtree of m leaves found in direction dir under
+// the parent p by replacing the parent with its other child.
+// A nil parent removes the root. The path to the parent is retraced
+// by following key from the root, making the nodes above the parent
+// modifiable on the way.
+func (t *Tree[V]) detach(key Key, p *Inner[V], dir, m int) {
+	if p == nil {
+		// Removing the only node in the tree
+		t.root = Node[V]{}
+	} else {
+		n := &t.root
+		for n.Inner != p {
+			// Uncount the leaves on the path above the parent
+			inner := t.own(n).Inner
+			if c := inner.counter(); c != nil {
+				*c -= m
+			}
+			n = &inner.child[key.Direction(inner.bit)]
+		}
+		// Replace parent with sibling
+		*n = p.child[dir^1]
+	}
+	t.nums -= m
+	t.mods++
+}
+
+// upsert returns the leaf with the given key, inserting a new leaf
+// holding the zero value of V if the key does not exist.
+// The inserted result reports whether a new leaf was inserted.
+// The returned leaf is owned by the tree and can be modified in place.
+func (t *Tree[V]) upsert(key Key) (leaf *Leaf[V], inserted bool) {
+	var zero V
+	leaf = t.findLeaf(key)
+	if leaf == nil {
+		// Tree is empty, create first leaf
+		leaf = t.newLeaf(key, zero)
+		t.root.Leaf = leaf
+		t.nums++
+		t.mods++
+		return leaf, true
+	}
+
+	bit := leaf.Key.Critbit(key)
+	if bit == -1 {
+		// Key already exists
+		if leaf.owner != t.owner {
+			leaf = t.ownLeaf(key)
+		}
+		return leaf, false
+	}
+
+	// Insert new internal node at the appropriate position
+	leaf = t.newLeaf(key, zero)
+	t.insertLeaf(leaf, bit)
+	return leaf, true
+}
+
 // insertNode creates a new internal node with the given critical bit
 // and inserts it at the specified position in the tree.
 // The existing node becomes one child,
 // and the new leaf becomes the other child.
 func (t *Tree[V]) insertNode(n *Node[V], leaf *Leaf[V], bit int) {
 	dir := leaf.Key.Direction(bit)
-	inner := new(Inner[V])
+	inner := t.newInner(bit)
 	inner.child[dir].Leaf = leaf
 	inner.child[dir^1] = *n
-	inner.bit = bit
+	if c := inner.counter(); c != nil {
+		*c = n.len() + 1
+	}
 	*n = Node[V]{Inner: inner}
 	t.nums++
+	t.mods++
 }
@@ -1,7 +1,9 @@
 package critbit
 
 import (
+	"iter"
 	"math/rand/v2"
+	"slices"
 	"testing"
 )
 
@@ -121,6 +123,69 @@ func TestTreeUint32(t *testing.T) {
 			i++
 		}
 	})
+	t.Run("Backward", func(t *testing.T) {
+		i := len(dataset) - 1
+		for key, val := range m.Backward() {
+			data := dataset[i]
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+			i--
+		}
+		if i != -1 {
+			t.Errorf("want %v; but got %v", -1, i)
+		}
+	})
+	t.Run("Backward break", func(t *testing.T) {
+		i := 0
+		for range m.Backward() {
+			if i >= len(dataset)/2 {
+				break
+			}
+			i++
+		}
+	})
+	t.Run("KeysBackward", func(t *testing.T) {
+		i := len(dataset) - 1
+		for key := range m.KeysBackward() {
+			data := dataset[i]
+			if !key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, key)
+			}
+			i--
+		}
+	})
+	t.Run("KeysBackward break", func(t *testing.T) {
+		i := 0
+		for range m.KeysBackward() {
+			if i >= len(dataset)/2 {
+				break
+			}
+			i++
+		}
+	})
+	t.Run("ValuesBackward", func(t *testing.T) {
+		i := len(dataset) - 1
+		for val := range m.ValuesBackward() {
+			data := dataset[i]
+			if val != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, val)
+			}
+			i--
+		}
+	})
+	t.Run("ValuesBackward break", func(t *testing.T) {
+		i := 0
+		for range m.ValuesBackward() {
+			if i >= len(dataset)/2 {
+				break
+			}
+			i++
+		}
+	})
 	t.Run("Reverse", func(t *testing.T) {
 		i := len(dataset) - 1
 		s := NewScanner(m.root, true)
@@ -139,6 +204,27 @@ func TestTreeUint32(t *testing.T) {
 			i--
 		}
 	})
+	t.Run("Scanner", func(t *testing.T) {
+		i := len(dataset) - 1
+		s := m.Scanner(true)
+		for {
+			leaf := s.Scan()
+			if leaf == nil {
+				break
+			}
+			data := dataset[i]
+			if !leaf.Key.Equal(data.Key) {
+				t.Errorf("want %v; but got %v", data.Key, leaf.Key)
+			}
+			if leaf.Value != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, leaf.Value)
+			}
+			i--
+		}
+		if i != -1 {
+			t.Errorf("want %v; but got %v", -1, i)
+		}
+	})
 	t.Run("Delete", func(t *testing.T) {
 		for _, p := range r.Perm(N) {
 			data := dataset[p]
@@ -167,3 +253,372 @@ func TestTreeUint32(t *testing.T) {
 		}
 	})
 }
+
+func TestTreeSwap(t *testing.T) {
+	N := 256
+	r := rand.New(rand.NewPCG(1, 1))
+	var m Tree[uint32]
+	dataset := setupDataset(N)
+	t.Run("SetIfAbsent", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			actual, inserted := m.SetIfAbsent(data.Key, data.Value)
+			if !inserted {
+				t.Errorf("want %v; but got %v", true, inserted)
+			}
+			if actual != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, actual)
+			}
+		}
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			actual, inserted := m.SetIfAbsent(data.Key, data.Value+1)
+			if inserted {
+				t.Errorf("want %v; but got %v", false, inserted)
+			}
+			if actual != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, actual)
+			}
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+	})
+	t.Run("Swap", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			old, replaced := m.Swap(data.Key, data.Value*2)
+			if !replaced {
+				t.Errorf("want %v; but got %v", true, replaced)
+			}
+			if old != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, old)
+			}
+		}
+		for _, data := range dataset {
+			val, _ := m.Get(data.Key)
+			if val != data.Value*2 {
+				t.Errorf("want %v; but got %v", data.Value*2, val)
+			}
+		}
+	})
+	t.Run("LoadAndDelete", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			val, loaded := m.LoadAndDelete(data.Key)
+			if !loaded {
+				t.Errorf("want %v; but got %v", true, loaded)
+			}
+			if val != data.Value*2 {
+				t.Errorf("want %v; but got %v", data.Value*2, val)
+			}
+			// already deleted
+			_, loaded = m.LoadAndDelete(data.Key)
+			if loaded {
+				t.Errorf("want %v; but got %v", false, loaded)
+			}
+		}
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+	})
+	t.Run("Swap absent", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			old, replaced := m.Swap(data.Key, data.Value)
+			if replaced {
+				t.Errorf("want %v; but got %v", false, replaced)
+			}
+			if old != 0 {
+				t.Errorf("want %v; but got %v", 0, old)
+			}
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+	})
+}
+
+func TestTreeUpdate(t *testing.T) {
+	N := 256
+	r := rand.New(rand.NewPCG(1, 1))
+	var m Tree[uint32]
+	dataset := setupDataset(N)
+	t.Run("insert", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+				if exists {
+					t.Errorf("want %v; but got %v", false, exists)
+				}
+				return data.Value, true
+			})
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+	})
+	t.Run("replace", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+				if !exists {
+					t.Errorf("want %v; but got %v", true, exists)
+				}
+				if old != data.Value {
+					t.Errorf("want %v; but got %v", data.Value, old)
+				}
+				return old + 1, true
+			})
+		}
+		for _, data := range dataset {
+			val, _ := m.Get(data.Key)
+			if val != data.Value+1 {
+				t.Errorf("want %v; but got %v", data.Value+1, val)
+			}
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			m.Update(data.Key, func(old uint32, exists bool) (uint32, bool) {
+				return 0, false
+			})
+			_, found := m.Get(data.Key)
+			if found {
+				t.Fatalf("%x exists", data.Key)
+			}
+		}
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+	})
+	t.Run("no insert", func(t *testing.T) {
+		m.Update(dataset[0].Key, func(old uint32, exists bool) (uint32, bool) {
+			return 1, false
+		})
+		if m.Len() != 0 {
+			t.Errorf("want %v; but got %v", 0, m.Len())
+		}
+	})
+}
+
+func TestTreeGetPtr(t *testing.T) {
+	N := 256
+	r := rand.New(rand.NewPCG(1, 1))
+	var m Tree[uint32]
+	dataset := setupDataset(N)
+	t.Run("GetOrInsertPtr", func(t *testing.T) {
+		for _, p := range r.Perm(N) {
+			data := dataset[p]
+			ptr := m.GetOrInsertPtr(data.Key)
+			if *ptr != 0 {
+				t.Errorf("want %v; but got %v", 0, *ptr)
+			}
+			*ptr = data.Value
+		}
+		if m.Len() != N {
+			t.Errorf("want %v; but got %v", N, m.Len())
+		}
+		for _, data := range dataset {
+			ptr := m.GetOrInsertPtr(data.Key)
+			if *ptr != data.Value {
+				t.Errorf("want %v; but got %v", data.Value, *ptr)
+			}
+		}
+	})
+	t.Run("GetPtr", func(t *testing.T) {
+		for _, data := range dataset {
+			ptr := m.GetPtr(data.Key)
+			if ptr == nil {
+				t.Fatalf("%x not found", data.Key)
+			}
+			*ptr += 1
+		}
+		for _, data := range dataset {
+			val, _ := m.Get(data.Key)
+			if val != data.Value+1 {
+				t.Errorf("want %v; but got %v", data.Value+1, val)
+			}
+		}
+	})
+	t.Run("observes Set", func(t *testing.T) {
+		data := dataset[0]
+		ptr := m.GetPtr(data.Key)
+		m.Set(data.Key, 100)
+		if *ptr != 100 {
+			t.Errorf("want %v; but got %v", 100, *ptr)
+		}
+	})
+	t.Run("after Delete", func(t *testing.T) {
+		data := dataset[0]
+		ptr := m.GetPtr(data.Key)
+		m.Delete(data.Key)
+		*ptr = 200
+		if m.GetPtr(data.Key) != nil {
+			t.Fatalf("%x exists", data.Key)
+		}
+		val := *m.GetOrInsertPtr(data.Key)
+		if val != 0 {
+			t.Errorf("want %v; but got %v", 0, val)
+		}
+	})
+}
+
+func TestTreeModifyDuringIteration(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	dataset := setupBitsDataset(r, 512)
+	var q Key // query of the prefix iterators, chosen for each round
+
+	tests := []struct {
+		name    string
+		reverse bool
+		seq     func(m *Tree[uint32]) iter.Seq2[Key, uint32]
+		// whether the key is iterated over
+		in func(key Key) bool
+	}{
+		{
+			name: "All",
+			seq:  (*Tree[uint32]).All,
+			in:   func(Key) bool { return true },
+		},
+		{
+			name:    "Backward",
+			reverse: true,
+			seq:     (*Tree[uint32]).Backward,
+			in:      func(Key) bool { return true },
+		},
+		{
+			name: "Range",
+			seq: func(m *Tree[uint32]) iter.Seq2[Key, uint32] {
+				return m.Range(Exclusive(dataset[100].Key), Inclusive(dataset[400].Key))
+			},
+			in: func(key Key) bool {
+				return inBounds(key, Exclusive(dataset[100].Key), Inclusive(dataset[400].Key))
+			},
+		},
+		{
+			name: "WithPrefix",
+			seq: func(m *Tree[uint32]) iter.Seq2[Key, uint32] {
+				return m.WithPrefix(BitsKey([]byte{0x40}, 2))
+			},
+			in: func(key Key) bool { return key.HasPrefix(BitsKey([]byte{0x40}, 2)) },
+		},
+		{
+			name: "Prefixes",
+			seq: func(m *Tree[uint32]) iter.Seq2[Key, uint32] {
+				return m.Prefixes(q)
+			},
+			in: func(key Key) bool { return q.HasPrefix(key) },
+		},
+		{
+			name:    "PrefixesBackward",
+			reverse: true,
+			seq: func(m *Tree[uint32]) iter.Seq2[Key, uint32] {
+				return m.PrefixesBackward(q)
+			},
+			in: func(key Key) bool { return q.HasPrefix(key) },
+		},
+		{
+			name: "Overlapping",
+			seq: func(m *Tree[uint32]) iter.Seq2[Key, uint32] {
+				return m.Overlapping(q)
+			},
+			in: func(key Key) bool { return q.HasPrefix(key) || key.HasPrefix(q) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for range 20 {
+				q = BitsKey(dataset[r.IntN(len(dataset))].Key.Data, 16)
+				var m Tree[uint32]
+				want := make([]int, len(dataset))
+				for i := range want {
+					want[i] = -1
+				}
+				for _, p := range r.Perm(len(dataset))[:len(dataset)/2] {
+					m.Set(dataset[p].Key, dataset[p].Value)
+					want[p] = int(dataset[p].Value)
+				}
+				// keys present during the whole iteration
+				always := make([]bool, len(dataset))
+				for i, v := range want {
+					always[i] = v >= 0
+				}
+				seen := make([]bool, len(dataset))
+				prev := -1
+				for key, val := range tc.seq(&m) {
+					i, found := slices.BinarySearchFunc(dataset, key, func(data TestData, key Key) int {
+						return data.Key.Compare(key)
+					})
+					if !found || want[i] < 0 {
+						t.Fatalf("yielded absent key %v", key)
+					}
+					if val != uint32(want[i]) {
+						t.Fatalf("want %v; but got %v", want[i], val)
+					}
+					if prev >= 0 && (i > prev) == tc.reverse {
+						t.Fatalf("%v yielded after %v", key, dataset[prev].Key)
+					}
+					prev = i
+					seen[i] = true
+
+					switch r.IntN(4) {
+					case 0:
+						m.Delete(key)
+						want[i] = -1
+					case 1:
+						// Shares the nodes, so the next change copies them
+						m.Clone()
+					}
+					mutate(r, &m, dataset, want)
+					for j, v := range want {
+						always[j] = always[j] && v >= 0
+					}
+				}
+				checkTree(t, &m, dataset, want)
+				for i, data := range dataset {
+					if always[i] && tc.in(data.Key) && !seen[i] {
+						t.Fatalf("%v not yielded", data.Key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTreeModifyDuringPrefixes(t *testing.T) {
+	keys := []Key{
+		BitsKey([]byte{0x80}, 1), // 1
+		BitsKey([]byte{0x80}, 2), // 10
+		BitsKey([]byte{0x80}, 3), // 100
+		BitsKey([]byte{0xc0}, 2), // 11
+	}
+	q := BitsKey([]byte{0x80}, 8)
+	tests := []struct {
+		name string
+		seq  func(m *Tree[int]) iter.Seq2[Key, int]
+	}{
+		{"Prefixes", func(m *Tree[int]) iter.Seq2[Key, int] { return m.Prefixes(q) }},
+		{"Overlapping", func(m *Tree[int]) iter.Seq2[Key, int] { return m.Overlapping(q) }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var m Tree[int]
+			for i, key := range keys {
+				m.Set(key, i)
+			}
+			var got []int
+			for key, val := range tc.seq(&m) {
+				got = append(got, val)
+				if key.Equal(keys[0]) {
+					m.Delete(keys[1])
+					m.Delete(keys[2])
+				}
+			}
+			if want := []int{0}; !slices.Equal(got, want) {
+				t.Errorf("want %v; but got %v", want, got)
+			}
+		})
+	}
+}